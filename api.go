@@ -0,0 +1,92 @@
+//go:build windows || darwin || (linux && gui)
+
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"autokeypress/internal/control"
+	"autokeypress/internal/core"
+)
+
+// errNoKeys is returned when Start finds nothing to run.
+var errNoKeys = errors.New("add at least one enabled key with a positive interval")
+
+// controlFuncs adapts a window's own closures to control.Controller, so the
+// API edits the same list the window shows. Each UI makes sure they run
+// where it is safe to touch its widgets.
+type controlFuncs struct {
+	entries func() []control.Listed
+	add     func(entry *core.KeyEntry) error
+	update  func(index int, entry *core.KeyEntry) error
+	remove  func(index int) error
+	start   func() ([]string, error)
+	stop    func()
+	pause   func()
+	resume  func()
+}
+
+func (c controlFuncs) Entries() []control.Listed {
+	return c.entries()
+}
+
+func (c controlFuncs) AddEntry(entry *core.KeyEntry) error {
+	return c.add(entry)
+}
+
+func (c controlFuncs) UpdateEntry(index int, entry *core.KeyEntry) error {
+	return c.update(index, entry)
+}
+
+func (c controlFuncs) RemoveEntry(index int) error {
+	return c.remove(index)
+}
+
+func (c controlFuncs) Start() ([]string, error) {
+	return c.start()
+}
+
+func (c controlFuncs) Stop()   { c.stop() }
+func (c controlFuncs) Pause()  { c.pause() }
+func (c controlFuncs) Resume() { c.resume() }
+
+// checkSupported reports why injector cannot send entry, so the API can
+// refuse it before it reaches the list rather than showing a dialog.
+func checkSupported(entry *core.KeyEntry, injector core.Injector) error {
+	enabled := *entry
+	enabled.Enabled = true
+	if _, errs := core.BuildTasks([]*core.KeyEntry{&enabled}, injector); len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// controlAPI serves the control API while the setting is on.
+type controlAPI struct {
+	server *control.Server
+}
+
+// apply starts or stops serving to match enabled.
+func (a *controlAPI) apply(enabled bool, ctl control.Controller, runner *core.Runner) error {
+	if !enabled {
+		a.Close()
+		return nil
+	}
+	if a.server != nil {
+		return nil
+	}
+	path, err := control.SocketPath()
+	if err != nil {
+		return err
+	}
+	a.server, err = control.Listen(path, ctl, runner)
+	return err
+}
+
+func (a *controlAPI) Close() {
+	if a.server != nil {
+		a.server.Close()
+		a.server = nil
+	}
+}
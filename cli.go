@@ -0,0 +1,308 @@
+//go:build windows || darwin || linux
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"autokeypress/internal/core"
+	"autokeypress/internal/profile"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+const runUsage = `usage: autokeypress run [PROFILE] [--key KEY [--interval MS] [--offset MS] [--jitter J] [--hold H]]...
+                        [--type TEXT | --type-file PATH [--type-delay MS] ...]...
+                        [--delay D] [--duration D] [--seed N] [--max-failures N]
+
+Presses keys without opening a window until interrupted (Ctrl+C, SIGTERM),
+until --duration elapses, or until every key has reached the stop condition
+set in its profile. PROFILE is a profile file or the name of a saved profile;
+--key entries are added to it. Each --interval, --offset, --jitter and --hold
+applies to the --key before it; keys without an interval use 1000 ms.
+--offset delays that key's presses to stagger it against the others, --jitter
+is ±ms or a percentage ("50", "10%"), --hold is ms or a range ("30-80").
+--type and --type-file add an entry typing that text, or the file's text,
+instead of a key; --type-delay waits that long between its characters. TEXT
+takes \n and \t escapes and template fields such as {{.N}}, the run number.
+KEY can also be a mouse action: CLICK, RIGHTCLICK, DOUBLECLICK, "MOVE X Y",
+"MOVEBY DX DY" or "SCROLL N". --delay waits before the first press. --seed
+makes the random timing repeat between runs. --max-failures stops a key after
+that many failed presses in a row; the exit status is then 1.
+`
+
+// keyFlags collects repeated --key/--interval flags in command-line order.
+type keyFlags struct {
+	entries []*core.KeyEntry
+}
+
+type keyFlag struct{ flags *keyFlags }
+
+func (f keyFlag) String() string { return "" }
+
+func (f keyFlag) Set(value string) error {
+	f.flags.entries = append(f.flags.entries, &core.KeyEntry{
+		Key:        strings.TrimSpace(value),
+		IntervalMS: 1000,
+		Enabled:    true,
+	})
+	return nil
+}
+
+type intervalFlag struct{ flags *keyFlags }
+
+func (f intervalFlag) String() string { return "" }
+
+func (f intervalFlag) Set(value string) error {
+	entry, err := f.flags.last("--interval")
+	if err != nil {
+		return err
+	}
+	interval := core.ParseInterval(value)
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval %q: enter a positive interval in ms", value)
+	}
+	entry.IntervalMS = interval
+	return nil
+}
+
+type offsetFlag struct{ flags *keyFlags }
+
+func (f offsetFlag) String() string { return "" }
+
+func (f offsetFlag) Set(value string) error {
+	entry, err := f.flags.last("--offset")
+	if err != nil {
+		return err
+	}
+	entry.OffsetMS, err = core.ParseDurationMS(value)
+	return err
+}
+
+type jitterFlag struct{ flags *keyFlags }
+
+func (f jitterFlag) String() string { return "" }
+
+func (f jitterFlag) Set(value string) error {
+	entry, err := f.flags.last("--jitter")
+	if err != nil {
+		return err
+	}
+	entry.JitterMS, entry.JitterPercent, err = core.ParseJitter(value)
+	return err
+}
+
+type holdFlag struct{ flags *keyFlags }
+
+func (f holdFlag) String() string { return "" }
+
+func (f holdFlag) Set(value string) error {
+	entry, err := f.flags.last("--hold")
+	if err != nil {
+		return err
+	}
+	entry.HoldMinMS, entry.HoldMaxMS, err = core.ParseHold(value)
+	return err
+}
+
+// typeFlag adds a text entry: --type sets its text and --type-file (file
+// true) the file to read it from.
+type typeFlag struct {
+	flags *keyFlags
+	file  bool
+}
+
+func (f typeFlag) String() string { return "" }
+
+func (f typeFlag) Set(value string) error {
+	entry := &core.KeyEntry{IntervalMS: 1000, Enabled: true}
+	if f.file {
+		entry.TextFile = value
+	} else {
+		entry.Text = value
+	}
+	if !entry.IsText() {
+		return fmt.Errorf("empty text")
+	}
+	f.flags.entries = append(f.flags.entries, entry)
+	return nil
+}
+
+type typeDelayFlag struct{ flags *keyFlags }
+
+func (f typeDelayFlag) String() string { return "" }
+
+func (f typeDelayFlag) Set(value string) error {
+	entry, err := f.flags.last("--type-delay")
+	if err != nil {
+		return err
+	}
+	entry.TypeDelayMS, err = core.ParseDurationMS(value)
+	return err
+}
+
+// last returns the entry a per-key flag such as --interval applies to.
+func (f *keyFlags) last(name string) (*core.KeyEntry, error) {
+	if len(f.entries) == 0 {
+		return nil, fmt.Errorf("%s must follow a --key or --type", name)
+	}
+	return f.entries[len(f.entries)-1], nil
+}
+
+// runCommand implements the headless "run" subcommand and returns the process
+// exit code.
+func runCommand(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), runUsage)
+	}
+	var keys keyFlags
+	fs.Var(keyFlag{&keys}, "key", "key to press (repeatable)")
+	fs.Var(intervalFlag{&keys}, "interval", "interval in ms for the preceding --key")
+	fs.Var(offsetFlag{&keys}, "offset", "initial offset for the preceding --key")
+	fs.Var(jitterFlag{&keys}, "jitter", "interval jitter for the preceding --key")
+	fs.Var(holdFlag{&keys}, "hold", "hold time for the preceding --key")
+	fs.Var(typeFlag{flags: &keys}, "type", "text to type (repeatable)")
+	fs.Var(typeFlag{flags: &keys, file: true}, "type-file", "file whose text to type (repeatable)")
+	fs.Var(typeDelayFlag{&keys}, "type-delay", "delay between characters for the preceding --type")
+	delay := fs.Duration("delay", 0, "wait this long before the first press")
+	duration := fs.Duration("duration", 0, "stop after this long (0 runs until interrupted)")
+	seed := fs.Uint64("seed", 0, "seed for the random timing (0 picks one per run)")
+	maxFailures := fs.Int("max-failures", 0, "stop a key after this many failed presses in a row (0 never stops)")
+	if err := parseInterspersed(fs, args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "run: expected at most one profile")
+		fs.Usage()
+		return exitUsage
+	}
+
+	var entries []*core.KeyEntry
+	if fs.NArg() == 1 {
+		loaded, err := profile.Load(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "run:", err)
+			return exitError
+		}
+		entries = loaded.Entries
+	}
+	entries = append(entries, keys.entries...)
+	if len(entries) == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	return runHeadless(entries, *delay, *duration, *seed, *maxFailures)
+}
+
+// openRunInjector opens the input device for runHeadless; tests replace it
+// with a fake.
+var openRunInjector = openInjector
+
+// runHeadless builds the same tasks the Start button builds and runs them
+// until a signal arrives, duration (if non-zero) elapses or every task has
+// finished. Unlike the UI it refuses to start when any key fails to parse.
+// It returns exitError if a key was stopped after maxFailures failed presses.
+func runHeadless(entries []*core.KeyEntry, delay, duration time.Duration, seed uint64, maxFailures int) int {
+	// Check the key syntax before touching the input device so typos are
+	// reported as usage errors even where injection is unavailable.
+	if _, errors := core.BuildTasks(entries, nil); len(errors) > 0 {
+		fmt.Fprintln(os.Stderr, strings.Join(errors, "\n"))
+		return exitUsage
+	}
+
+	injector, closeInjector, err := openRunInjector()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer closeInjector()
+
+	tasks, errors := core.BuildTasks(entries, injector)
+	if len(errors) > 0 {
+		fmt.Fprintln(os.Stderr, strings.Join(errors, "\n"))
+		return exitUsage
+	}
+	if len(tasks) == 0 {
+		fmt.Fprintln(os.Stderr, "Add at least one enabled key with a positive interval.")
+		return exitUsage
+	}
+
+	runner := core.NewRunner(injector)
+	if seed != 0 {
+		runner.Seed(seed)
+	}
+	runner.StopAfterFailures(maxFailures)
+	finished := make(chan struct{})
+	runner.OnFinished(func() { close(finished) })
+	if delay == 0 {
+		fmt.Println("Status: running (Ctrl+C to stop)")
+	}
+	runner.StartIn(tasks, delay, func(seconds int) {
+		if seconds > 0 {
+			fmt.Printf("Status: starting in %ds\n", seconds)
+		} else {
+			fmt.Println("Status: running (Ctrl+C to stop)")
+		}
+	})
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	var timeout <-chan time.Time
+	if duration > 0 {
+		timer := time.NewTimer(delay + duration)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-signals:
+	case <-timeout:
+	case <-finished:
+	}
+
+	runner.Stop()
+	fmt.Println("Status: idle")
+	stats := runner.Stats()
+	code := exitOK
+	for _, task := range tasks {
+		fmt.Printf("  %s: %s\n", task.Entry.DisplayKey(), statsSummary(stats[task.Entry]))
+		if stats[task.Entry].GaveUp {
+			code = exitError
+		}
+	}
+	return code
+}
+
+// parseInterspersed parses flags that may appear before or after positional
+// arguments, which the flag package alone does not allow.
+func parseInterspersed(fs *flag.FlagSet, args []string) error {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	return fs.Parse(append([]string{"--"}, positional...))
+}
@@ -0,0 +1,212 @@
+//go:build windows || darwin || linux
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"autokeypress/internal/core"
+)
+
+// countingInjector counts the keys pressed through it. Keys in fail are
+// counted but refused.
+type countingInjector struct {
+	mu      sync.Mutex
+	presses map[core.Key]int
+	fail    map[core.Key]bool
+}
+
+func (i *countingInjector) Supports(core.Key) bool { return true }
+
+func (i *countingInjector) KeyDown(key core.Key) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.presses[key]++
+	if i.fail[key] {
+		return errors.New("refused")
+	}
+	return nil
+}
+
+func (i *countingInjector) KeyUp(core.Key) error           { return nil }
+func (i *countingInjector) TypeRune(rune) error            { return nil }
+func (i *countingInjector) MoveMouse(int, int, bool) error { return nil }
+func (i *countingInjector) Scroll(int) error               { return nil }
+
+func (i *countingInjector) count(key core.Key) int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.presses[key]
+}
+
+// fakeRunInjector makes runHeadless press keys through the returned injector,
+// or fail to open one when err is not nil.
+func fakeRunInjector(t *testing.T, err error) *countingInjector {
+	t.Helper()
+	injector := &countingInjector{presses: map[core.Key]int{}}
+	saved := openRunInjector
+	openRunInjector = func() (core.Injector, func(), error) {
+		if err != nil {
+			return nil, nil, err
+		}
+		return injector, func() {}, nil
+	}
+	t.Cleanup(func() { openRunInjector = saved })
+	return injector
+}
+
+// silence discards what the command prints for the rest of the test.
+func silence(t *testing.T) {
+	t.Helper()
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = null, null
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		null.Close()
+	})
+}
+
+func TestRunCommandExitCodes(t *testing.T) {
+	profilePath := filepath.Join(t.TempDir(), "keys.yaml")
+	if err := os.WriteFile(profilePath, []byte("entries:\n  - key: F6\n    interval_ms: 5\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	limitedPath := filepath.Join(t.TempDir(), "limited.yaml")
+	if err := os.WriteFile(limitedPath, []byte("entries:\n  - key: F6\n    interval_ms: 5\n    max_presses: 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		openErr error
+		want    int
+	}{
+		{name: "help", args: []string{"--help"}, want: exitOK},
+		{name: "unknown flag", args: []string{"--speed", "2"}, want: exitUsage},
+		{name: "nothing to press", args: nil, want: exitUsage},
+		{name: "two profiles", args: []string{"a", "b"}, want: exitUsage},
+		{name: "interval before key", args: []string{"--interval", "5", "--key", "A"}, want: exitUsage},
+		{name: "bad interval", args: []string{"--key", "A", "--interval", "0"}, want: exitUsage},
+		{name: "jitter before key", args: []string{"--jitter", "10%", "--key", "A"}, want: exitUsage},
+		{name: "offset before key", args: []string{"--offset", "5", "--key", "A"}, want: exitUsage},
+		{name: "bad offset", args: []string{"--key", "A", "--offset", "soon"}, want: exitUsage},
+		{name: "bad delay", args: []string{"--key", "A", "--delay", "5"}, want: exitUsage},
+		{name: "bad jitter", args: []string{"--key", "A", "--jitter", "200%"}, want: exitUsage},
+		{name: "bad hold", args: []string{"--key", "A", "--hold", "80-30"}, want: exitUsage},
+		{name: "bad seed", args: []string{"--key", "A", "--seed", "-1"}, want: exitUsage},
+		{name: "bad max failures", args: []string{"--key", "A", "--max-failures", "many"}, want: exitUsage},
+		{name: "bad key", args: []string{"--key", "hello"}, want: exitUsage},
+		{name: "empty text", args: []string{"--type", ""}, want: exitUsage},
+		{name: "type delay before text", args: []string{"--type-delay", "20", "--type", "hi"}, want: exitUsage},
+		{name: "bad text template", args: []string{"--type", "{{.N"}, want: exitUsage},
+		{name: "types text", args: []string{"--type", `hello\n`, "--interval", "5", "--type-delay", "1", "--duration", "30ms"}, want: exitOK},
+		{name: "missing profile", args: []string{filepath.Join(t.TempDir(), "none.yaml")}, want: exitError},
+		{name: "no input device", args: []string{"--key", "F5"}, openErr: errors.New("no device"), want: exitError},
+		{name: "runs for the duration", args: []string{"--key", "F5", "--interval", "5", "--duration", "30ms"}, want: exitOK},
+		{name: "jitter, hold and seed", args: []string{"--key", "F5", "--interval", "5", "--jitter", "2", "--hold", "1-2", "--seed", "7", "--duration", "30ms"}, want: exitOK},
+		{name: "delay and offset", args: []string{"--delay", "10ms", "--key", "F5", "--interval", "5", "--offset", "2", "--duration", "30ms"}, want: exitOK},
+		{name: "stops when every key finishes", args: []string{limitedPath}, want: exitOK},
+		{name: "profile and keys", args: []string{"--duration", "30ms", profilePath, "--key", "F5", "--interval", "5"}, want: exitOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			silence(t)
+			fakeRunInjector(t, tt.openErr)
+			if got := runCommand(tt.args); got != tt.want {
+				t.Errorf("runCommand(%q) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunHeadlessPresses(t *testing.T) {
+	silence(t)
+	injector := fakeRunInjector(t, nil)
+	entries := []*core.KeyEntry{
+		{Key: "F5", IntervalMS: 5, Enabled: true},
+		{Key: "F6", IntervalMS: 5},
+	}
+	if got := runHeadless(entries, 0, 50*time.Millisecond, 0, 0); got != exitOK {
+		t.Fatalf("runHeadless = %d, want %d", got, exitOK)
+	}
+	if injector.count(core.KeyF5) == 0 {
+		t.Error("F5 was never pressed")
+	}
+	if injector.count(core.KeyF6) != 0 {
+		t.Error("the disabled F6 was pressed")
+	}
+
+	if got := runHeadless(entries[1:], 0, 50*time.Millisecond, 0, 0); got != exitUsage {
+		t.Errorf("runHeadless with nothing enabled = %d, want %d", got, exitUsage)
+	}
+}
+
+func TestRunHeadlessGivesUpOnFailures(t *testing.T) {
+	silence(t)
+	injector := fakeRunInjector(t, nil)
+	injector.fail = map[core.Key]bool{core.KeyF6: true}
+	entries := []*core.KeyEntry{
+		{Key: "F5", IntervalMS: 5, Enabled: true},
+		{Key: "F6", IntervalMS: 5, Enabled: true},
+	}
+	if got := runHeadless(entries, 0, 100*time.Millisecond, 0, 3); got != exitError {
+		t.Errorf("runHeadless = %d, want %d", got, exitError)
+	}
+	if got := injector.count(core.KeyF6); got != 3 {
+		t.Errorf("F6 was pressed %d times, want 3 before giving up", got)
+	}
+	if injector.count(core.KeyF5) <= 3 {
+		t.Error("F5 stopped along with F6")
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	tests := []struct {
+		args       []string
+		positional []string
+		verbose    bool
+		name       string
+		wantErr    bool
+	}{
+		{args: []string{"a", "b"}, positional: []string{"a", "b"}},
+		{args: []string{"-v", "a"}, positional: []string{"a"}, verbose: true},
+		{args: []string{"a", "-v", "b", "--name", "x"}, positional: []string{"a", "b"}, verbose: true, name: "x"},
+		{args: []string{"--name=x", "a"}, positional: []string{"a"}, name: "x"},
+		{args: []string{"a", "--", "-v"}, positional: []string{"a", "-v"}},
+		{args: []string{"a", "--name"}, wantErr: true},
+		{args: []string{"--bogus"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		verbose := fs.Bool("v", false, "")
+		name := fs.String("name", "", "")
+		err := parseInterspersed(fs, tt.args)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseInterspersed(%q) succeeded", tt.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseInterspersed(%q): %v", tt.args, err)
+			continue
+		}
+		if !slices.Equal(fs.Args(), tt.positional) || *verbose != tt.verbose || *name != tt.name {
+			t.Errorf("parseInterspersed(%q) = %q, -v %t, --name %q", tt.args, fs.Args(), *verbose, *name)
+		}
+	}
+}
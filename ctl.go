@@ -0,0 +1,242 @@
+//go:build windows || darwin || linux
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"autokeypress/internal/control"
+	"autokeypress/internal/core"
+	"autokeypress/internal/profile"
+)
+
+// exitNotServing is returned by ctl when no instance serves the control API.
+const exitNotServing = 3
+
+const ctlUsage = `usage: autokeypress ctl [--json] [--socket PATH] COMMAND
+
+Drives an open window through its local control API (turn it on in
+Options > Settings). Commands:
+  status                  show the state and each entry's statistics
+  start | stop | pause | resume
+  add KEY INTERVAL_MS     add a key, started at once if the keys are running
+  set N KEY INTERVAL_MS   replace entry N (numbered as in status)
+  remove N                remove entry N
+--json prints the API's response as JSON. Exit status: 0 done, 1 the
+instance refused the request, 2 bad arguments, 3 no instance is serving the
+API.
+`
+
+// ctlRequest is one parsed ctl command. It returns what to print: a
+// control.Status, a control.EntryStatus or, for remove, the removed index.
+type ctlRequest func(client *control.Client) (any, error)
+
+// ctlCommand implements the "ctl" subcommand and returns the process exit
+// code.
+func ctlCommand(args []string) int {
+	fs := flag.NewFlagSet("ctl", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), ctlUsage)
+	}
+	asJSON := fs.Bool("json", false, "print the response as JSON")
+	socket := fs.String("socket", "", "control socket (default: the app's)")
+	if err := parseInterspersed(fs, args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	request, err := parseCtl(fs.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ctl:", err)
+		fs.Usage()
+		return exitUsage
+	}
+
+	path := *socket
+	if path == "" {
+		if path, err = control.SocketPath(); err != nil {
+			fmt.Fprintln(os.Stderr, "ctl:", err)
+			return exitError
+		}
+	}
+	result, err := request(control.NewClient(path))
+	if errors.Is(err, control.ErrNotServing) {
+		fmt.Fprintf(os.Stderr, "ctl: %v\nIs the app open with Options > Settings > Serve the local control API on?\n", err)
+		return exitNotServing
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ctl:", err)
+		return exitError
+	}
+
+	if *asJSON {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ctl:", err)
+			return exitError
+		}
+		fmt.Println(string(data))
+		return exitOK
+	}
+	switch result := result.(type) {
+	case control.Status:
+		printCtlStatus(result)
+	case control.EntryStatus:
+		fmt.Println(formatCtlEntry(result))
+	case int:
+		fmt.Printf("Removed entry %d\n", result)
+	}
+	return exitOK
+}
+
+// ctlCommands maps each command to how many arguments it takes.
+var ctlCommands = map[string]int{
+	"status": 0, "start": 0, "stop": 0, "pause": 0, "resume": 0,
+	"add": 2, "set": 3, "remove": 1,
+}
+
+// parseCtl checks the command and its arguments without contacting the
+// instance.
+func parseCtl(args []string) (ctlRequest, error) {
+	if len(args) == 0 {
+		return nil, errors.New("missing command")
+	}
+	command, args := args[0], args[1:]
+	wantArgs, ok := ctlCommands[command]
+	if !ok {
+		return nil, fmt.Errorf("unknown command %q", command)
+	}
+	if len(args) != wantArgs {
+		return nil, fmt.Errorf("%s takes %d arguments", command, wantArgs)
+	}
+
+	switch command {
+	case "status":
+		return func(c *control.Client) (any, error) { return c.Status() }, nil
+	case "start":
+		return func(c *control.Client) (any, error) {
+			status, err := c.Start()
+			for _, skipped := range status.Skipped {
+				fmt.Fprintln(os.Stderr, "skipped:", skipped)
+			}
+			return status, err
+		}, nil
+	case "stop":
+		return func(c *control.Client) (any, error) { return c.Stop() }, nil
+	case "pause":
+		return func(c *control.Client) (any, error) { return c.Pause() }, nil
+	case "resume":
+		return func(c *control.Client) (any, error) { return c.Resume() }, nil
+	case "add":
+		entry, err := ctlEntry(args[0], args[1])
+		if err != nil {
+			return nil, err
+		}
+		return func(c *control.Client) (any, error) { return c.Add(entry) }, nil
+	case "set":
+		index, err := ctlIndex(args[0])
+		if err != nil {
+			return nil, err
+		}
+		entry, err := ctlEntry(args[1], args[2])
+		if err != nil {
+			return nil, err
+		}
+		return func(c *control.Client) (any, error) { return c.Update(index, entry) }, nil
+	case "remove":
+		index, err := ctlIndex(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return func(c *control.Client) (any, error) { return index, c.Remove(index) }, nil
+	default:
+		return nil, fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func ctlEntry(key, interval string) (profile.Entry, error) {
+	entry := &core.KeyEntry{
+		Key:        strings.TrimSpace(key),
+		IntervalMS: core.ParseInterval(interval),
+		Enabled:    true,
+	}
+	if !core.ValidEntry(entry.Key, entry.IntervalMS) {
+		return profile.Entry{}, fmt.Errorf("invalid entry %q %q: enter a key and a positive interval in ms", key, interval)
+	}
+	if _, errs := core.BuildTasks([]*core.KeyEntry{entry}, nil); len(errs) > 0 {
+		return profile.Entry{}, errors.New(strings.Join(errs, "\n"))
+	}
+	return profile.NewEntry(entry), nil
+}
+
+func ctlIndex(arg string) (int, error) {
+	index, err := strconv.Atoi(arg)
+	if err != nil || index < 1 {
+		return 0, fmt.Errorf("invalid entry number %q", arg)
+	}
+	return index, nil
+}
+
+func printCtlStatus(status control.Status) {
+	switch {
+	case status.Paused:
+		fmt.Println("Status: paused")
+	case status.Running:
+		fmt.Println("Status: running")
+	default:
+		fmt.Println("Status: idle")
+	}
+	for _, entry := range status.Entries {
+		fmt.Println("  " + formatCtlEntry(entry))
+	}
+}
+
+// formatCtlEntry is one line of ctl output, for example
+// "2. A every 500 ms: 12 presses, last 15:04:05, actual 501 ms (+1)".
+func formatCtlEntry(entry control.EntryStatus) string {
+	line := fmt.Sprintf("%d. %s every %d ms", entry.Index, ctlDisplayKey(entry.Entry), entry.IntervalMS)
+	if entry.Enabled != nil && !*entry.Enabled {
+		line += " (disabled)"
+	}
+	if stats := entry.Stats; stats != nil {
+		line += ": " + ctlStatsSummary(stats)
+	}
+	return line
+}
+
+func ctlDisplayKey(entry profile.Entry) string {
+	keyEntry, err := entry.KeyEntry()
+	if err != nil {
+		return entry.Key
+	}
+	return keyEntry.DisplayKey()
+}
+
+// ctlStatsSummary matches statsSummary for the API's statistics.
+func ctlStatsSummary(stats *control.Stats) string {
+	if stats.Presses == 0 || stats.LastPress == nil {
+		return "no presses yet"
+	}
+	summary := fmt.Sprintf("%d presses, last %s", stats.Presses, stats.LastPress.Local().Format("15:04:05"))
+	if stats.Failures > 0 {
+		summary += fmt.Sprintf(", %d failed", stats.Failures)
+	}
+	if stats.LastError != "" {
+		if stats.GaveUp {
+			summary += " (stopped: " + stats.LastError + ")"
+		} else {
+			summary += " (" + stats.LastError + ")"
+		}
+	}
+	if stats.MeanIntervalMS != 0 {
+		summary += fmt.Sprintf(", actual %d ms (%+d)", stats.MeanIntervalMS, stats.DriftMS)
+	}
+	return summary
+}
@@ -0,0 +1,145 @@
+//go:build windows || darwin || linux
+
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"autokeypress/internal/control"
+	"autokeypress/internal/core"
+)
+
+// listController is a window's entry list without the window.
+type listController struct {
+	mu      sync.Mutex
+	runner  *core.Runner
+	entries []*core.KeyEntry
+}
+
+func (c *listController) Entries() []control.Listed {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return control.Snapshot(c.entries)
+}
+
+func (c *listController) list() []*core.KeyEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*core.KeyEntry(nil), c.entries...)
+}
+
+func (c *listController) AddEntry(entry *core.KeyEntry) error {
+	c.mu.Lock()
+	c.entries = append(c.entries, entry)
+	c.mu.Unlock()
+	return c.runner.UpdateEntry(nil, entry)
+}
+
+func (c *listController) UpdateEntry(index int, entry *core.KeyEntry) error {
+	c.mu.Lock()
+	old := c.entries[index]
+	c.entries[index] = entry
+	c.mu.Unlock()
+	return c.runner.UpdateEntry(old, entry)
+}
+
+func (c *listController) RemoveEntry(index int) error {
+	c.mu.Lock()
+	removed := c.entries[index]
+	c.entries = append(c.entries[:index], c.entries[index+1:]...)
+	c.mu.Unlock()
+	c.runner.RemoveEntry(removed)
+	return nil
+}
+
+func (c *listController) Start() ([]string, error) {
+	tasks, skipped := core.BuildTasks(c.list(), nil)
+	if len(tasks) == 0 {
+		return skipped, errors.New("nothing to start")
+	}
+	c.runner.Start(tasks)
+	return skipped, nil
+}
+
+func (c *listController) Stop()   { c.runner.Stop() }
+func (c *listController) Pause()  { c.runner.Pause() }
+func (c *listController) Resume() { c.runner.Resume() }
+
+func TestParseCtlErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"bogus"}, `unknown command "bogus"`},
+		{[]string{"bogus", "1"}, `unknown command "bogus"`},
+		{[]string{"add", "A"}, "add takes 2 arguments"},
+		{[]string{"status", "now"}, "status takes 0 arguments"},
+	}
+	for _, tt := range tests {
+		if _, err := parseCtl(tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseCtl(%q) = %v, want an error containing %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestCtlCommandExitCodes(t *testing.T) {
+	runner := core.NewRunner(&countingInjector{presses: map[core.Key]int{}})
+	served := filepath.Join(t.TempDir(), "control.sock")
+	server, err := control.Listen(served, &listController{runner: runner}, runner)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() {
+		runner.Stop()
+		server.Close()
+	})
+	missing := filepath.Join(t.TempDir(), "control.sock")
+
+	// The steps share one instance and run in order.
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "help", args: []string{"--help"}, want: exitOK},
+		{name: "no command", args: []string{"--socket", served}, want: exitUsage},
+		{name: "unknown command", args: []string{"--socket", served, "restart"}, want: exitUsage},
+		{name: "missing interval", args: []string{"--socket", served, "add", "A"}, want: exitUsage},
+		{name: "bad key", args: []string{"--socket", served, "add", "hello", "500"}, want: exitUsage},
+		{name: "bad entry number", args: []string{"--socket", served, "remove", "first"}, want: exitUsage},
+		{name: "not serving", args: []string{"--socket", missing, "status"}, want: exitNotServing},
+		{name: "start with nothing", args: []string{"--socket", served, "start"}, want: exitError},
+		{name: "add", args: []string{"--socket", served, "add", "A", "10"}, want: exitOK},
+		{name: "add as JSON", args: []string{"add", "CTRL+S", "20", "--socket", served, "--json"}, want: exitOK},
+		{name: "set", args: []string{"--socket", served, "set", "2", "B", "20"}, want: exitOK},
+		{name: "set missing entry", args: []string{"--socket", served, "set", "3", "B", "20"}, want: exitError},
+		{name: "start", args: []string{"--socket", served, "start"}, want: exitOK},
+		{name: "pause", args: []string{"--socket", served, "pause"}, want: exitOK},
+		{name: "status", args: []string{"--socket", served, "status"}, want: exitOK},
+		{name: "resume", args: []string{"--socket", served, "resume"}, want: exitOK},
+		{name: "stop", args: []string{"--socket", served, "stop"}, want: exitOK},
+		{name: "pause while stopped", args: []string{"--socket", served, "pause"}, want: exitError},
+		{name: "remove", args: []string{"--socket", served, "remove", "1"}, want: exitOK},
+		{name: "status as JSON", args: []string{"--json", "--socket", served, "status"}, want: exitOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			silence(t)
+			if got := ctlCommand(tt.args); got != tt.want {
+				t.Errorf("ctlCommand(%q) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+
+	status, err := control.NewClient(served).Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(status.Entries) != 1 || status.Entries[0].Key != "B" {
+		t.Errorf("entries after the commands = %+v, want only B", status.Entries)
+	}
+}
@@ -8,6 +8,8 @@ require (
 	fyne.io/fyne/v2 v2.4.4
 	github.com/lxn/walk v0.0.0-20210112085537-c389da54e794
 	github.com/micmonay/keybd_event v1.0.0
+	golang.design/x/hotkey v0.6.4
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -38,6 +40,5 @@ require (
 	golang.org/x/sys v0.40.0 // indirect
 	golang.org/x/text v0.13.0 // indirect
 	gopkg.in/Knetic/govaluate.v3 v3.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	honnef.co/go/js/dom v0.0.0-20210725211120-f030747120f2 // indirect
 )
@@ -0,0 +1,114 @@
+//go:build windows || darwin || (linux && gui)
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"autokeypress/internal/core"
+	"autokeypress/internal/settings"
+	"golang.design/x/hotkey"
+)
+
+// globalHotkeys holds the system-wide hotkeys that control the runner while
+// another app has focus.
+type globalHotkeys struct {
+	keys []*hotkey.Hotkey
+	done chan struct{}
+}
+
+// registerHotkeys registers the toggle and emergency-stop hotkeys from s.
+// Callbacks run on a background goroutine. Hotkeys that cannot be parsed or
+// registered are skipped and reported in errors.
+func registerHotkeys(s settings.Settings, onToggle, onStop func()) (*globalHotkeys, []string) {
+	g := &globalHotkeys{done: make(chan struct{})}
+	var errors []string
+
+	bindings := []struct {
+		name     string
+		spec     string
+		callback func()
+	}{
+		{"Start/stop hotkey", s.ToggleHotkey, onToggle},
+		{"Emergency stop hotkey", s.StopHotkey, onStop},
+	}
+	for _, binding := range bindings {
+		if strings.TrimSpace(binding.spec) == "" {
+			continue
+		}
+		hk, err := newHotkey(binding.spec)
+		if err == nil {
+			err = hk.Register()
+		}
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("%s %s: %v", binding.name, binding.spec, err))
+			continue
+		}
+		g.keys = append(g.keys, hk)
+		go g.listen(hk, binding.callback)
+	}
+	return g, errors
+}
+
+func (g *globalHotkeys) listen(hk *hotkey.Hotkey, callback func()) {
+	keydown := hk.Keydown()
+	for {
+		select {
+		case <-g.done:
+			return
+		case _, ok := <-keydown:
+			if !ok {
+				return
+			}
+			callback()
+		}
+	}
+}
+
+// Close unregisters every hotkey so new settings can be registered.
+func (g *globalHotkeys) Close() {
+	close(g.done)
+	for _, hk := range g.keys {
+		_ = hk.Unregister()
+	}
+	g.keys = nil
+}
+
+// newHotkey builds the hotkey for spec, see parseHotkey.
+func newHotkey(spec string) (*hotkey.Hotkey, error) {
+	task, err := parseHotkey(spec)
+	if err != nil {
+		return nil, err
+	}
+	code, ok := hotkeyCode(task.Key)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key: %s", spec)
+	}
+	var mods []hotkey.Modifier
+	for _, modifier := range task.Modifiers {
+		mods = append(mods, hotkeyModifiers[modifier])
+	}
+	return hotkey.New(mods, code), nil
+}
+
+// parseHotkey parses spec with the same syntax as key entries (for example
+// CTRL+SHIFT+F12). A hotkey needs a named key, so a single letter or digit is
+// looked up by name rather than typed as Unicode.
+func parseHotkey(spec string) (core.KeyTask, error) {
+	task, err := core.ParseKey(spec)
+	if err != nil {
+		return core.KeyTask{}, err
+	}
+	if task.UseUnicode {
+		key, ok := core.LookupKey(spec)
+		if !ok {
+			return core.KeyTask{}, fmt.Errorf("unsupported key: %s", spec)
+		}
+		task = core.KeyTask{Key: key}
+	}
+	if task.Key.IsModifier() {
+		return core.KeyTask{}, fmt.Errorf("a hotkey needs a key besides modifiers")
+	}
+	return task, nil
+}
@@ -0,0 +1,22 @@
+//go:build darwin
+
+package main
+
+import (
+	"autokeypress/internal/core"
+	"golang.design/x/hotkey"
+)
+
+var hotkeyModifiers = map[core.Key]hotkey.Modifier{
+	core.KeyCtrl:  hotkey.ModCtrl,
+	core.KeyShift: hotkey.ModShift,
+	core.KeyAlt:   hotkey.ModOption,
+	core.KeyMeta:  hotkey.ModCmd,
+}
+
+// hotkeyCode returns the virtual key code for key; hotkeys use the same
+// codes the injector posts. Media keys cannot be hotkeys.
+func hotkeyCode(key core.Key) (hotkey.Key, bool) {
+	code, ok := macKeyCodes[key]
+	return hotkey.Key(code), ok
+}
@@ -0,0 +1,86 @@
+//go:build linux && gui
+
+package main
+
+import (
+	"autokeypress/internal/core"
+	"golang.design/x/hotkey"
+)
+
+var hotkeyModifiers = map[core.Key]hotkey.Modifier{
+	core.KeyCtrl:  hotkey.ModCtrl,
+	core.KeyShift: hotkey.ModShift,
+	core.KeyAlt:   hotkey.Mod1,
+	core.KeyMeta:  hotkey.Mod4,
+}
+
+// x11Keysyms are the X keysyms XGrabKey is given; unlike the injector they
+// are not evdev codes. Media keys cannot be hotkeys.
+var x11Keysyms = map[core.Key]hotkey.Key{
+	core.KeyA: 0x61, core.KeyB: 0x62, core.KeyC: 0x63, core.KeyD: 0x64, core.KeyE: 0x65,
+	core.KeyF: 0x66, core.KeyG: 0x67, core.KeyH: 0x68, core.KeyI: 0x69, core.KeyJ: 0x6a,
+	core.KeyK: 0x6b, core.KeyL: 0x6c, core.KeyM: 0x6d, core.KeyN: 0x6e, core.KeyO: 0x6f,
+	core.KeyP: 0x70, core.KeyQ: 0x71, core.KeyR: 0x72, core.KeyS: 0x73, core.KeyT: 0x74,
+	core.KeyU: 0x75, core.KeyV: 0x76, core.KeyW: 0x77, core.KeyX: 0x78, core.KeyY: 0x79,
+	core.KeyZ: 0x7a,
+
+	core.Key1: 0x31, core.Key2: 0x32, core.Key3: 0x33, core.Key4: 0x34, core.Key5: 0x35,
+	core.Key6: 0x36, core.Key7: 0x37, core.Key8: 0x38, core.Key9: 0x39, core.Key0: 0x30,
+
+	core.KeyF1: 0xffbe, core.KeyF2: 0xffbf, core.KeyF3: 0xffc0, core.KeyF4: 0xffc1,
+	core.KeyF5: 0xffc2, core.KeyF6: 0xffc3, core.KeyF7: 0xffc4, core.KeyF8: 0xffc5,
+	core.KeyF9: 0xffc6, core.KeyF10: 0xffc7, core.KeyF11: 0xffc8, core.KeyF12: 0xffc9,
+	core.KeyF13: 0xffca, core.KeyF14: 0xffcb, core.KeyF15: 0xffcc, core.KeyF16: 0xffcd,
+	core.KeyF17: 0xffce, core.KeyF18: 0xffcf, core.KeyF19: 0xffd0, core.KeyF20: 0xffd1,
+	core.KeyF21: 0xffd2, core.KeyF22: 0xffd3, core.KeyF23: 0xffd4, core.KeyF24: 0xffd5,
+
+	core.KeySpace: 0x20,
+	core.KeyEnter: 0xff0d,
+	core.KeyEsc:   0xff1b,
+	core.KeyTab:   0xff09,
+	core.KeyUp:    0xff52,
+	core.KeyDown:  0xff54,
+	core.KeyLeft:  0xff51,
+	core.KeyRight: 0xff53,
+
+	core.KeyHome:        0xff50,
+	core.KeyEnd:         0xff57,
+	core.KeyPageUp:      0xff55,
+	core.KeyPageDown:    0xff56,
+	core.KeyInsert:      0xff63,
+	core.KeyDelete:      0xffff,
+	core.KeyBackspace:   0xff08,
+	core.KeyCapsLock:    0xffe5,
+	core.KeyNumLock:     0xff7f,
+	core.KeyScrollLock:  0xff14,
+	core.KeyPrintScreen: 0xff61,
+	core.KeyPause:       0xff13,
+
+	core.KeyNumpad0: 0xffb0, core.KeyNumpad1: 0xffb1, core.KeyNumpad2: 0xffb2, core.KeyNumpad3: 0xffb3,
+	core.KeyNumpad4: 0xffb4, core.KeyNumpad5: 0xffb5, core.KeyNumpad6: 0xffb6, core.KeyNumpad7: 0xffb7,
+	core.KeyNumpad8: 0xffb8, core.KeyNumpad9: 0xffb9,
+	core.KeyNumpadAdd:      0xffab,
+	core.KeyNumpadSubtract: 0xffad,
+	core.KeyNumpadMultiply: 0xffaa,
+	core.KeyNumpadDivide:   0xffaf,
+	core.KeyNumpadDecimal:  0xffae,
+	core.KeyNumpadEnter:    0xff8d,
+
+	core.KeyComma:        0x2c,
+	core.KeyPeriod:       0x2e,
+	core.KeyMinus:        0x2d,
+	core.KeyEqual:        0x3d,
+	core.KeySlash:        0x2f,
+	core.KeyBackslash:    0x5c,
+	core.KeySemicolon:    0x3b,
+	core.KeyQuote:        0x27,
+	core.KeyBackquote:    0x60,
+	core.KeyLeftBracket:  0x5b,
+	core.KeyRightBracket: 0x5d,
+}
+
+// hotkeyCode returns the X keysym for key.
+func hotkeyCode(key core.Key) (hotkey.Key, bool) {
+	code, ok := x11Keysyms[key]
+	return code, ok
+}
@@ -0,0 +1,61 @@
+//go:build windows || darwin || (linux && gui)
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"autokeypress/internal/core"
+	"autokeypress/internal/settings"
+)
+
+func TestParseHotkey(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    core.KeyTask
+		wantErr bool
+	}{
+		{spec: "F9", want: core.KeyTask{Key: core.KeyF9}},
+		{spec: "a", want: core.KeyTask{Key: core.KeyA}},
+		{spec: "7", want: core.KeyTask{Key: core.Key7}},
+		{spec: "CTRL+SHIFT+F12", want: core.KeyTask{Key: core.KeyF12, Modifiers: []core.Key{core.KeyCtrl, core.KeyShift}}},
+		{spec: "alt + p", want: core.KeyTask{Key: core.KeyP, Modifiers: []core.Key{core.KeyAlt}}},
+
+		{spec: "", wantErr: true},
+		{spec: "é", wantErr: true},
+		{spec: "SHIFT", wantErr: true},
+		{spec: "CTRL+SHIFT", wantErr: true},
+		{spec: "CTRL+hello", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseHotkey(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseHotkey(%q) = %+v, want an error", tt.spec, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseHotkey(%q) = %+v, %v, want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestDefaultHotkeysHaveCodes(t *testing.T) {
+	defaults := settings.Defaults()
+	for _, spec := range []string{defaults.ToggleHotkey, defaults.StopHotkey} {
+		task, err := parseHotkey(spec)
+		if err != nil {
+			t.Fatalf("parseHotkey(%q): %v", spec, err)
+		}
+		if _, ok := hotkeyCode(task.Key); !ok {
+			t.Errorf("%s has no hotkey code", spec)
+		}
+		for _, modifier := range task.Modifiers {
+			if _, ok := hotkeyModifiers[modifier]; !ok {
+				t.Errorf("%s has no hotkey modifier", modifier)
+			}
+		}
+	}
+}
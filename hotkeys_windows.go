@@ -0,0 +1,36 @@
+//go:build windows
+
+package main
+
+import (
+	"autokeypress/internal/core"
+	"golang.design/x/hotkey"
+)
+
+var hotkeyModifiers = map[core.Key]hotkey.Modifier{
+	core.KeyCtrl:  hotkey.ModCtrl,
+	core.KeyShift: hotkey.ModShift,
+	core.KeyAlt:   hotkey.ModAlt,
+	core.KeyMeta:  hotkey.ModWin,
+}
+
+var procMapVirtualKey = user32.NewProc("MapVirtualKeyW")
+
+const mapvkVscToVk = 1
+
+// hotkeyCode returns the virtual-key code RegisterHotKey expects. Keys sent
+// by scan code are translated with MapVirtualKey.
+func hotkeyCode(key core.Key) (hotkey.Key, bool) {
+	code, ok := windowsKeyCodes[key]
+	if !ok {
+		return 0, false
+	}
+	if code >= vkOffset {
+		return hotkey.Key(code - vkOffset), true
+	}
+	vk, _, _ := procMapVirtualKey.Call(uintptr(code), mapvkVscToVk)
+	if vk == 0 {
+		return 0, false
+	}
+	return hotkey.Key(vk), true
+}
@@ -0,0 +1,423 @@
+//go:build darwin
+
+package main
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework ApplicationServices -framework AppKit
+#include <ApplicationServices/ApplicationServices.h>
+#import <AppKit/AppKit.h>
+
+// Media and volume keys are not virtual keys on macOS: the system only reacts
+// to NSSystemDefined events with the "aux control button" subtype. It returns
+// 0 when the event cannot be created.
+static int akpMediaKey(int key, int down) {
+	@autoreleasepool {
+		NSInteger state = down ? 0xa : 0xb;
+		NSEvent *event = [NSEvent otherEventWithType:NSEventTypeSystemDefined
+			location:NSZeroPoint
+			modifierFlags:(NSEventModifierFlags)(state << 8)
+			timestamp:0
+			windowNumber:0
+			context:nil
+			subtype:8
+			data1:((key << 16) | (state << 8))
+			data2:-1];
+		if (event == nil) {
+			return 0;
+		}
+		CGEventPost(kCGHIDEventTap, [event CGEvent]);
+		return 1;
+	}
+}
+
+// akpPointerLocation is where the pointer is, in the global display
+// coordinates of mouse events (origin at the top left of the main display).
+static CGPoint akpPointerLocation(void) {
+	CGEventRef event = CGEventCreate(NULL);
+	if (event == NULL) {
+		return CGPointZero;
+	}
+	CGPoint point = CGEventGetLocation(event);
+	CFRelease(event);
+	return point;
+}
+
+// akpPostMouse posts a mouse event at point. clicks is the click count apps
+// read double clicks from; dx and dy are the motion games read instead of
+// the position. It returns 0 when the event cannot be created.
+static int akpPostMouse(CGEventType type, CGMouseButton button, CGPoint point, int64_t clicks, int64_t dx, int64_t dy, CGEventFlags flags) {
+	CGEventRef event = CGEventCreateMouseEvent(NULL, type, point, button);
+	if (event == NULL) {
+		return 0;
+	}
+	if (clicks > 0) {
+		CGEventSetIntegerValueField(event, kCGMouseEventClickState, clicks);
+	}
+	if (dx != 0 || dy != 0) {
+		CGEventSetIntegerValueField(event, kCGMouseEventDeltaX, dx);
+		CGEventSetIntegerValueField(event, kCGMouseEventDeltaY, dy);
+	}
+	if (flags != 0) {
+		CGEventSetFlags(event, flags);
+	}
+	CGEventPost(kCGHIDEventTap, event);
+	CFRelease(event);
+	return 1;
+}
+
+// akpScroll turns the wheel by lines. CGEventCreateScrollWheelEvent is
+// variadic, which cgo cannot call.
+static int akpScroll(int32_t lines) {
+	CGEventRef event = CGEventCreateScrollWheelEvent(NULL, kCGScrollEventUnitLine, 1, lines);
+	if (event == NULL) {
+		return 0;
+	}
+	CGEventPost(kCGHIDEventTap, event);
+	CFRelease(event);
+	return 1;
+}
+
+static double akpDoubleClickInterval(void) {
+	return [NSEvent doubleClickInterval];
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf16"
+	"unsafe"
+
+	"autokeypress/internal/core"
+)
+
+// macInjector posts CoreGraphics keyboard and mouse events to the HID event
+// tap. Posting a modifier key alone does not make later synthetic events
+// carry it, so held modifiers are tracked and stamped on every event with
+// CGEventSetFlags. Apps read double clicks and drags from the events rather
+// than their timing, so quick clicks of a button are counted and moves made
+// with a button down are sent as drags.
+type macInjector struct {
+	mu    sync.Mutex
+	flags C.CGEventFlags
+
+	buttons   []core.Key
+	lastClick time.Time
+	clicked   core.Key
+	clicks    int64
+}
+
+// errAccessibility is reported for every press while the app is not trusted:
+// macOS then drops synthetic events without any error of its own.
+var errAccessibility = errors.New("macOS is blocking key events: grant Accessibility permission to autokeypress (or the terminal running it) in System Settings > Privacy & Security > Accessibility")
+
+func openInjector() (core.Injector, func(), error) {
+	if err := checkAccessibility(); err != nil {
+		return nil, nil, err
+	}
+	return &macInjector{}, func() {}, nil
+}
+
+// checkAccessibility reports errAccessibility unless the process may post
+// events to other apps.
+func checkAccessibility() error {
+	if C.AXIsProcessTrusted() == 0 {
+		return errAccessibility
+	}
+	return nil
+}
+
+// eventError explains why an event could not be created, which is usually
+// the missing Accessibility permission.
+func eventError() error {
+	if err := checkAccessibility(); err != nil {
+		return err
+	}
+	return errors.New("cannot create a keyboard event")
+}
+
+func (m *macInjector) Supports(key core.Key) bool {
+	if _, ok := macMediaKeys[key]; ok {
+		return true
+	}
+	if _, ok := macButtons[key]; ok {
+		return true
+	}
+	_, ok := macKeyCodes[key]
+	return ok
+}
+
+func (m *macInjector) KeyDown(key core.Key) error {
+	return m.key(key, true)
+}
+
+func (m *macInjector) KeyUp(key core.Key) error {
+	return m.key(key, false)
+}
+
+func (m *macInjector) key(key core.Key, down bool) error {
+	if media, ok := macMediaKeys[key]; ok {
+		if err := checkAccessibility(); err != nil {
+			return err
+		}
+		var pressed C.int
+		if down {
+			pressed = 1
+		}
+		if C.akpMediaKey(media, pressed) == 0 {
+			return eventError()
+		}
+		return nil
+	}
+
+	if button, ok := macButtons[key]; ok {
+		return m.button(key, button, down)
+	}
+
+	code, ok := macKeyCodes[key]
+	if !ok {
+		return fmt.Errorf("unsupported key code: %d", key)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if flag, ok := macModifierFlags[key]; ok {
+		if down {
+			m.flags |= flag
+		} else {
+			m.flags &^= flag
+		}
+	}
+	return keyEvent(code, down, m.flags)
+}
+
+func (m *macInjector) TypeRune(r rune) error {
+	return keyTapUnicode(r)
+}
+
+// button presses or releases a mouse button where the pointer is. A press
+// within the double-click interval of the last one, with the pointer not
+// moved in between, counts as the next click of a double or triple click.
+func (m *macInjector) button(key core.Key, button macButton, down bool) error {
+	if err := checkAccessibility(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	typ := button.up
+	if down {
+		typ = button.down
+		interval := time.Duration(float64(C.akpDoubleClickInterval()) * float64(time.Second))
+		if key == m.clicked && time.Since(m.lastClick) < interval {
+			m.clicks++
+		} else {
+			m.clicks = 1
+		}
+		m.clicked, m.lastClick = key, time.Now()
+		if !slices.Contains(m.buttons, key) {
+			m.buttons = append(m.buttons, key)
+		}
+	} else {
+		m.buttons = slices.DeleteFunc(m.buttons, func(held core.Key) bool { return held == key })
+	}
+	if C.akpPostMouse(typ, button.button, C.akpPointerLocation(), C.int64_t(m.clicks), 0, 0, m.flags) == 0 {
+		return eventError()
+	}
+	return nil
+}
+
+func (m *macInjector) MoveMouse(x, y int, relative bool) error {
+	if err := checkAccessibility(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	typ, button := C.CGEventType(C.kCGEventMouseMoved), C.CGMouseButton(C.kCGMouseButtonLeft)
+	if len(m.buttons) > 0 {
+		held := macButtons[m.buttons[0]]
+		typ, button = held.dragged, held.button
+	}
+	point := C.CGPoint{x: C.CGFloat(x), y: C.CGFloat(y)}
+	var dx, dy C.int64_t
+	if relative {
+		from := C.akpPointerLocation()
+		point = C.CGPoint{x: from.x + C.CGFloat(x), y: from.y + C.CGFloat(y)}
+		dx, dy = C.int64_t(x), C.int64_t(y)
+	}
+	m.clicked = core.KeyNone
+	if C.akpPostMouse(typ, button, point, 0, dx, dy, m.flags) == 0 {
+		return eventError()
+	}
+	return nil
+}
+
+func (m *macInjector) Scroll(notches int) error {
+	if err := checkAccessibility(); err != nil {
+		return err
+	}
+	if C.akpScroll(C.int32_t(notches)) == 0 {
+		return eventError()
+	}
+	return nil
+}
+
+// macKeyCodes maps keys to virtual key codes (Carbon Events.h, ANSI layout).
+var macKeyCodes = map[core.Key]C.CGKeyCode{
+	core.KeyA: 0, core.KeyB: 11, core.KeyC: 8, core.KeyD: 2, core.KeyE: 14,
+	core.KeyF: 3, core.KeyG: 5, core.KeyH: 4, core.KeyI: 34, core.KeyJ: 38,
+	core.KeyK: 40, core.KeyL: 37, core.KeyM: 46, core.KeyN: 45, core.KeyO: 31,
+	core.KeyP: 35, core.KeyQ: 12, core.KeyR: 15, core.KeyS: 1, core.KeyT: 17,
+	core.KeyU: 32, core.KeyV: 9, core.KeyW: 13, core.KeyX: 7, core.KeyY: 16,
+	core.KeyZ: 6,
+
+	core.Key0: 29, core.Key1: 18, core.Key2: 19, core.Key3: 20, core.Key4: 21,
+	core.Key5: 23, core.Key6: 22, core.Key7: 26, core.Key8: 28, core.Key9: 25,
+
+	core.KeyF1: 122, core.KeyF2: 120, core.KeyF3: 99, core.KeyF4: 118,
+	core.KeyF5: 96, core.KeyF6: 97, core.KeyF7: 98, core.KeyF8: 100,
+	core.KeyF9: 101, core.KeyF10: 109, core.KeyF11: 103, core.KeyF12: 111,
+	core.KeyF13: 105, core.KeyF14: 107, core.KeyF15: 113, core.KeyF16: 106,
+	core.KeyF17: 64, core.KeyF18: 79, core.KeyF19: 80, core.KeyF20: 90,
+
+	core.KeySpace: 49,
+	core.KeyEnter: 36,
+	core.KeyEsc:   53,
+	core.KeyTab:   48,
+	core.KeyUp:    126,
+	core.KeyDown:  125,
+	core.KeyLeft:  123,
+	core.KeyRight: 124,
+
+	// Mac keyboards have no Insert, Print Screen, Scroll Lock or Pause; Help
+	// sits where Insert is and Clear where Num Lock is.
+	core.KeyHome:      115,
+	core.KeyEnd:       119,
+	core.KeyPageUp:    116,
+	core.KeyPageDown:  121,
+	core.KeyInsert:    114,
+	core.KeyDelete:    117,
+	core.KeyBackspace: 51,
+	core.KeyCapsLock:  57,
+	core.KeyNumLock:   71,
+
+	core.KeyNumpad0: 82, core.KeyNumpad1: 83, core.KeyNumpad2: 84, core.KeyNumpad3: 85,
+	core.KeyNumpad4: 86, core.KeyNumpad5: 87, core.KeyNumpad6: 88, core.KeyNumpad7: 89,
+	core.KeyNumpad8: 91, core.KeyNumpad9: 92,
+	core.KeyNumpadAdd:      69,
+	core.KeyNumpadSubtract: 78,
+	core.KeyNumpadMultiply: 67,
+	core.KeyNumpadDivide:   75,
+	core.KeyNumpadDecimal:  65,
+	core.KeyNumpadEnter:    76,
+
+	core.KeyComma:        43,
+	core.KeyPeriod:       47,
+	core.KeyMinus:        27,
+	core.KeyEqual:        24,
+	core.KeySlash:        44,
+	core.KeyBackslash:    42,
+	core.KeySemicolon:    41,
+	core.KeyQuote:        39,
+	core.KeyBackquote:    50,
+	core.KeyLeftBracket:  33,
+	core.KeyRightBracket: 30,
+
+	core.KeyCtrl:  59,
+	core.KeyShift: 56,
+	core.KeyAlt:   58,
+	core.KeyMeta:  55,
+}
+
+// macMediaKeys maps media keys to NX_KEYTYPE_* codes (IOKit ev_keymap.h).
+var macMediaKeys = map[core.Key]C.int{
+	core.KeyVolumeUp:       0,
+	core.KeyVolumeDown:     1,
+	core.KeyVolumeMute:     7,
+	core.KeyMediaPlayPause: 16,
+	core.KeyMediaNext:      17,
+	core.KeyMediaPrev:      18,
+}
+
+// macButton is the events and CoreGraphics button of one mouse button.
+type macButton struct {
+	down, up, dragged C.CGEventType
+	button            C.CGMouseButton
+}
+
+var macButtons = map[core.Key]macButton{
+	core.KeyMouseLeft:   {C.kCGEventLeftMouseDown, C.kCGEventLeftMouseUp, C.kCGEventLeftMouseDragged, C.kCGMouseButtonLeft},
+	core.KeyMouseRight:  {C.kCGEventRightMouseDown, C.kCGEventRightMouseUp, C.kCGEventRightMouseDragged, C.kCGMouseButtonRight},
+	core.KeyMouseMiddle: {C.kCGEventOtherMouseDown, C.kCGEventOtherMouseUp, C.kCGEventOtherMouseDragged, C.kCGMouseButtonCenter},
+}
+
+var macModifierFlags = map[core.Key]C.CGEventFlags{
+	core.KeyCtrl:  C.kCGEventFlagMaskControl,
+	core.KeyShift: C.kCGEventFlagMaskShift,
+	core.KeyAlt:   C.kCGEventFlagMaskAlternate,
+	core.KeyMeta:  C.kCGEventFlagMaskCommand,
+}
+
+func keyEvent(code C.CGKeyCode, down bool, flags C.CGEventFlags) error {
+	if err := checkAccessibility(); err != nil {
+		return err
+	}
+	event := C.CGEventCreateKeyboardEvent(C.CGEventSourceRef(0), code, C.bool(down))
+	if event == C.CGEventRef(0) {
+		return eventError()
+	}
+	if flags != 0 {
+		C.CGEventSetFlags(event, flags)
+	}
+	C.CGEventPost(C.kCGHIDEventTap, event)
+	C.CFRelease(C.CFTypeRef(event))
+	return nil
+}
+
+func keyTapUnicode(r rune) error {
+	units := utf16.Encode([]rune{r})
+	if len(units) == 0 {
+		return nil
+	}
+	if err := checkAccessibility(); err != nil {
+		return err
+	}
+
+	eventDown := C.CGEventCreateKeyboardEvent(C.CGEventSourceRef(0), 0, C.bool(true))
+	eventUp := C.CGEventCreateKeyboardEvent(C.CGEventSourceRef(0), 0, C.bool(false))
+	if eventDown == C.CGEventRef(0) || eventUp == C.CGEventRef(0) {
+		if eventDown != C.CGEventRef(0) {
+			C.CFRelease(C.CFTypeRef(eventDown))
+		}
+		if eventUp != C.CGEventRef(0) {
+			C.CFRelease(C.CFTypeRef(eventUp))
+		}
+		return eventError()
+	}
+
+	C.CGEventKeyboardSetUnicodeString(
+		eventDown,
+		C.UniCharCount(len(units)),
+		(*C.UniChar)(unsafe.Pointer(&units[0])),
+	)
+	C.CGEventKeyboardSetUnicodeString(
+		eventUp,
+		C.UniCharCount(len(units)),
+		(*C.UniChar)(unsafe.Pointer(&units[0])),
+	)
+
+	C.CGEventPost(C.kCGHIDEventTap, eventDown)
+	C.CGEventPost(C.kCGHIDEventTap, eventUp)
+	C.CFRelease(C.CFTypeRef(eventDown))
+	C.CFRelease(C.CFTypeRef(eventUp))
+	return nil
+}
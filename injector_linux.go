@@ -0,0 +1,178 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"autokeypress/internal/core"
+)
+
+// linuxKeyboard is implemented by the uinput and XTEST backends, which also
+// drive the mouse. Key and button codes are always evdev codes.
+type linuxKeyboard interface {
+	Key(code uint16, down bool) error
+	TapRune(r rune) error
+	Button(code uint16, down bool) error
+	Move(x, y int, relative bool) error
+	Scroll(notches int) error
+	Close() error
+}
+
+// openLinuxKeyboard picks the injection backend. AUTOKEYPRESS_BACKEND can
+// force "uinput" or "xtest"; otherwise XTEST is preferred when an X display
+// is available since it needs no root, with uinput as the fallback.
+func openLinuxKeyboard() (linuxKeyboard, error) {
+	switch strings.ToLower(os.Getenv("AUTOKEYPRESS_BACKEND")) {
+	case "uinput":
+		return openUinputKeyboard()
+	case "xtest":
+		return openXTestKeyboard()
+	case "":
+	default:
+		return nil, fmt.Errorf("unknown backend: %s", os.Getenv("AUTOKEYPRESS_BACKEND"))
+	}
+
+	if os.Getenv("DISPLAY") != "" {
+		if keyboard, err := openXTestKeyboard(); err == nil {
+			return keyboard, nil
+		}
+	}
+	return openUinputKeyboard()
+}
+
+func openInjector() (core.Injector, func(), error) {
+	keyboard, err := openLinuxKeyboard()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &linuxInjector{keyboard: keyboard}, func() { keyboard.Close() }, nil
+}
+
+// linuxInjector adapts a linuxKeyboard backend to core.Injector.
+type linuxInjector struct {
+	keyboard linuxKeyboard
+}
+
+func (i *linuxInjector) Supports(key core.Key) bool {
+	_, ok := linuxKeyCodes[key]
+	_, button := linuxButtonCodes[key]
+	return ok || button
+}
+
+func (i *linuxInjector) KeyDown(key core.Key) error {
+	return i.key(key, true)
+}
+
+func (i *linuxInjector) KeyUp(key core.Key) error {
+	return i.key(key, false)
+}
+
+func (i *linuxInjector) TypeRune(r rune) error {
+	return i.keyboard.TapRune(r)
+}
+
+func (i *linuxInjector) MoveMouse(x, y int, relative bool) error {
+	return i.keyboard.Move(x, y, relative)
+}
+
+func (i *linuxInjector) Scroll(notches int) error {
+	return i.keyboard.Scroll(notches)
+}
+
+func (i *linuxInjector) key(key core.Key, down bool) error {
+	if code, ok := linuxButtonCodes[key]; ok {
+		return i.keyboard.Button(code, down)
+	}
+	code, ok := linuxKeyCodes[key]
+	if !ok {
+		return fmt.Errorf("unsupported key code: %d", key)
+	}
+	return i.keyboard.Key(code, down)
+}
+
+// linuxButtonCodes maps the mouse buttons to their evdev codes.
+var linuxButtonCodes = map[core.Key]uint16{
+	core.KeyMouseLeft:   btnLeft,
+	core.KeyMouseRight:  btnRight,
+	core.KeyMouseMiddle: btnMiddle,
+}
+
+// linuxKeyCodes maps keys to evdev codes (linux/input-event-codes.h).
+var linuxKeyCodes = map[core.Key]uint16{
+	core.KeyA: 30, core.KeyB: 48, core.KeyC: 46, core.KeyD: 32, core.KeyE: 18,
+	core.KeyF: 33, core.KeyG: 34, core.KeyH: 35, core.KeyI: 23, core.KeyJ: 36,
+	core.KeyK: 37, core.KeyL: 38, core.KeyM: 50, core.KeyN: 49, core.KeyO: 24,
+	core.KeyP: 25, core.KeyQ: 16, core.KeyR: 19, core.KeyS: 31, core.KeyT: 20,
+	core.KeyU: 22, core.KeyV: 47, core.KeyW: 17, core.KeyX: 45, core.KeyY: 21,
+	core.KeyZ: 44,
+
+	core.Key1: 2, core.Key2: 3, core.Key3: 4, core.Key4: 5, core.Key5: 6,
+	core.Key6: 7, core.Key7: 8, core.Key8: 9, core.Key9: 10, core.Key0: 11,
+
+	core.KeyF1: 59, core.KeyF2: 60, core.KeyF3: 61, core.KeyF4: 62,
+	core.KeyF5: 63, core.KeyF6: 64, core.KeyF7: 65, core.KeyF8: 66,
+	core.KeyF9: 67, core.KeyF10: 68, core.KeyF11: 87, core.KeyF12: 88,
+	core.KeyF13: 183, core.KeyF14: 184, core.KeyF15: 185, core.KeyF16: 186,
+	core.KeyF17: 187, core.KeyF18: 188, core.KeyF19: 189, core.KeyF20: 190,
+	core.KeyF21: 191, core.KeyF22: 192, core.KeyF23: 193, core.KeyF24: 194,
+
+	core.KeySpace: 57,
+	core.KeyEnter: 28,
+	core.KeyEsc:   1,
+	core.KeyTab:   15,
+	core.KeyUp:    103,
+	core.KeyDown:  108,
+	core.KeyLeft:  105,
+	core.KeyRight: 106,
+
+	core.KeyHome:        102,
+	core.KeyEnd:         107,
+	core.KeyPageUp:      104,
+	core.KeyPageDown:    109,
+	core.KeyInsert:      110,
+	core.KeyDelete:      111,
+	core.KeyBackspace:   14,
+	core.KeyCapsLock:    58,
+	core.KeyNumLock:     69,
+	core.KeyScrollLock:  70,
+	core.KeyPrintScreen: 99,
+	core.KeyPause:       119,
+
+	core.KeyNumpad0: 82, core.KeyNumpad1: 79, core.KeyNumpad2: 80, core.KeyNumpad3: 81,
+	core.KeyNumpad4: 75, core.KeyNumpad5: 76, core.KeyNumpad6: 77, core.KeyNumpad7: 71,
+	core.KeyNumpad8: 72, core.KeyNumpad9: 73,
+	core.KeyNumpadAdd:      78,
+	core.KeyNumpadSubtract: 74,
+	core.KeyNumpadMultiply: 55,
+	core.KeyNumpadDivide:   98,
+	core.KeyNumpadDecimal:  83,
+	core.KeyNumpadEnter:    96,
+
+	core.KeyComma:        51,
+	core.KeyPeriod:       52,
+	core.KeyMinus:        12,
+	core.KeyEqual:        13,
+	core.KeySlash:        53,
+	core.KeyBackslash:    43,
+	core.KeySemicolon:    39,
+	core.KeyQuote:        40,
+	core.KeyBackquote:    41,
+	core.KeyLeftBracket:  26,
+	core.KeyRightBracket: 27,
+
+	core.KeyVolumeUp:       115,
+	core.KeyVolumeDown:     114,
+	core.KeyVolumeMute:     113,
+	core.KeyMediaPlayPause: 164,
+	core.KeyMediaNext:      163,
+	core.KeyMediaPrev:      165,
+	core.KeyMediaStop:      166,
+
+	core.KeyCtrl:  29,
+	core.KeyShift: keyLeftShift,
+	core.KeyAlt:   56,
+	core.KeyMeta:  125,
+}
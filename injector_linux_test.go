@@ -0,0 +1,127 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"autokeypress/internal/core"
+)
+
+func TestOpenLinuxKeyboardUnknownBackend(t *testing.T) {
+	t.Setenv("AUTOKEYPRESS_BACKEND", "wayland")
+	keyboard, err := openLinuxKeyboard()
+	if err == nil {
+		keyboard.Close()
+		t.Fatal("openLinuxKeyboard accepted an unknown backend")
+	}
+	if want := "unknown backend: wayland"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
+
+func TestOpenXTestKeyboardWithoutDisplay(t *testing.T) {
+	t.Setenv("DISPLAY", "")
+	keyboard, err := openXTestKeyboard()
+	if err == nil {
+		keyboard.Close()
+		t.Fatal("openXTestKeyboard succeeded without a display")
+	}
+	if !strings.HasPrefix(err.Error(), "xtest: ") {
+		t.Errorf("error = %q, want an xtest: error", err)
+	}
+}
+
+// TestOpenLinuxKeyboardFallsBackToUinput checks that without a display the
+// uinput backend is tried, and that its failure is the one reported.
+func TestOpenLinuxKeyboardFallsBackToUinput(t *testing.T) {
+	if file, err := os.OpenFile("/dev/uinput", os.O_WRONLY, 0); err == nil {
+		file.Close()
+		t.Skip("/dev/uinput is writable here")
+	}
+	for _, backend := range []string{"", "uinput", "UINPUT"} {
+		t.Setenv("AUTOKEYPRESS_BACKEND", backend)
+		t.Setenv("DISPLAY", "")
+		keyboard, err := openLinuxKeyboard()
+		if err == nil {
+			keyboard.Close()
+			t.Fatalf("backend %q: openLinuxKeyboard succeeded without /dev/uinput", backend)
+		}
+		if !strings.HasPrefix(err.Error(), "uinput: ") {
+			t.Errorf("backend %q: error = %q, want a uinput: error", backend, err)
+		}
+	}
+}
+
+// fakeKeyboard records the calls a linuxInjector makes on its backend.
+type fakeKeyboard struct {
+	calls []string
+}
+
+func (k *fakeKeyboard) record(format string, args ...any) error {
+	k.calls = append(k.calls, fmt.Sprintf(format, args...))
+	return nil
+}
+
+func (k *fakeKeyboard) Key(code uint16, down bool) error {
+	return k.record("key %d %t", code, down)
+}
+
+func (k *fakeKeyboard) TapRune(r rune) error {
+	return k.record("rune %c", r)
+}
+
+func (k *fakeKeyboard) Button(code uint16, down bool) error {
+	return k.record("button %d %t", code, down)
+}
+
+func (k *fakeKeyboard) Move(x, y int, relative bool) error {
+	return k.record("move %d %d %t", x, y, relative)
+}
+
+func (k *fakeKeyboard) Scroll(notches int) error {
+	return k.record("scroll %d", notches)
+}
+
+func (k *fakeKeyboard) Close() error {
+	return nil
+}
+
+func TestLinuxInjectorMapsKeys(t *testing.T) {
+	keyboard := &fakeKeyboard{}
+	injector := &linuxInjector{keyboard: keyboard}
+
+	injector.KeyDown(core.KeyShift)
+	injector.KeyDown(core.KeyA)
+	injector.KeyUp(core.KeyA)
+	injector.KeyUp(core.KeyShift)
+	injector.TypeRune('x')
+	injector.KeyDown(core.KeyMouseRight)
+	injector.KeyUp(core.KeyMouseRight)
+	injector.MoveMouse(-5, 10, true)
+	injector.Scroll(-2)
+	if err := injector.KeyDown(core.KeyNone); err == nil {
+		t.Error("KeyDown(KeyNone) succeeded")
+	}
+
+	want := []string{
+		"key 42 true", "key 30 true", "key 30 false", "key 42 false", "rune x",
+		"button 273 true", "button 273 false", "move -5 10 true", "scroll -2",
+	}
+	if !slices.Equal(keyboard.calls, want) {
+		t.Errorf("calls = %q, want %q", keyboard.calls, want)
+	}
+}
+
+func TestLinuxKeyCodesCoverEveryKey(t *testing.T) {
+	injector := &linuxInjector{}
+	for key := core.KeyA; key <= core.KeyMeta; key++ {
+		if !injector.Supports(key) {
+			t.Errorf("%s has no evdev code", key)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+
+	"autokeypress/internal/core"
+	"github.com/micmonay/keybd_event"
+)
+
+// windowsInjector sends key presses and Unicode text through SendInput. Key
+// codes follow the keybd_event convention: scan codes as-is, virtual-key codes
+// offset by vkOffset. keybd_event's own Launching is not used because it
+// presses and releases modifiers in a fixed order.
+type windowsInjector struct{}
+
+func openInjector() (core.Injector, func(), error) {
+	return windowsInjector{}, func() {}, nil
+}
+
+func (windowsInjector) Supports(key core.Key) bool {
+	_, ok := windowsKeyCodes[key]
+	_, button := windowsButtonFlags[key]
+	return ok || button
+}
+
+func (windowsInjector) KeyDown(key core.Key) error {
+	if flags, ok := windowsButtonFlags[key]; ok {
+		return sendMouse(mouseInput{Flags: flags.down})
+	}
+	return sendKey(key, false)
+}
+
+func (windowsInjector) KeyUp(key core.Key) error {
+	if flags, ok := windowsButtonFlags[key]; ok {
+		return sendMouse(mouseInput{Flags: flags.up})
+	}
+	return sendKey(key, true)
+}
+
+func (windowsInjector) TypeRune(r rune) error {
+	return sendUnicode(r)
+}
+
+// MoveMouse sends absolute moves in the 0-65535 coordinates SendInput
+// spreads over the whole virtual desktop. Relative moves are subject to the
+// pointer speed setting, as a real mouse's are.
+func (windowsInjector) MoveMouse(x, y int, relative bool) error {
+	if relative {
+		return sendMouse(mouseInput{Dx: int32(x), Dy: int32(y), Flags: mouseeventfMove})
+	}
+	left, _, _ := procGetSystemMetrics.Call(smXVirtualScreen)
+	top, _, _ := procGetSystemMetrics.Call(smYVirtualScreen)
+	width, _, _ := procGetSystemMetrics.Call(smCXVirtualScreen)
+	height, _, _ := procGetSystemMetrics.Call(smCYVirtualScreen)
+	if int32(width) <= 1 || int32(height) <= 1 {
+		return fmt.Errorf("GetSystemMetrics: no virtual screen")
+	}
+	return sendMouse(mouseInput{
+		Dx:    int32((x - int(int32(left))) * 65535 / (int(int32(width)) - 1)),
+		Dy:    int32((y - int(int32(top))) * 65535 / (int(int32(height)) - 1)),
+		Flags: mouseeventfMove | mouseeventfAbsolute | mouseeventfVirtualDesk,
+	})
+}
+
+func (windowsInjector) Scroll(notches int) error {
+	return sendMouse(mouseInput{MouseData: uint32(int32(notches * wheelDelta)), Flags: mouseeventfWheel})
+}
+
+var windowsKeyCodes = map[core.Key]int{
+	core.KeyA: keybd_event.VK_A, core.KeyB: keybd_event.VK_B, core.KeyC: keybd_event.VK_C,
+	core.KeyD: keybd_event.VK_D, core.KeyE: keybd_event.VK_E, core.KeyF: keybd_event.VK_F,
+	core.KeyG: keybd_event.VK_G, core.KeyH: keybd_event.VK_H, core.KeyI: keybd_event.VK_I,
+	core.KeyJ: keybd_event.VK_J, core.KeyK: keybd_event.VK_K, core.KeyL: keybd_event.VK_L,
+	core.KeyM: keybd_event.VK_M, core.KeyN: keybd_event.VK_N, core.KeyO: keybd_event.VK_O,
+	core.KeyP: keybd_event.VK_P, core.KeyQ: keybd_event.VK_Q, core.KeyR: keybd_event.VK_R,
+	core.KeyS: keybd_event.VK_S, core.KeyT: keybd_event.VK_T, core.KeyU: keybd_event.VK_U,
+	core.KeyV: keybd_event.VK_V, core.KeyW: keybd_event.VK_W, core.KeyX: keybd_event.VK_X,
+	core.KeyY: keybd_event.VK_Y, core.KeyZ: keybd_event.VK_Z,
+
+	core.Key0: keybd_event.VK_0, core.Key1: keybd_event.VK_1, core.Key2: keybd_event.VK_2,
+	core.Key3: keybd_event.VK_3, core.Key4: keybd_event.VK_4, core.Key5: keybd_event.VK_5,
+	core.Key6: keybd_event.VK_6, core.Key7: keybd_event.VK_7, core.Key8: keybd_event.VK_8,
+	core.Key9: keybd_event.VK_9,
+
+	core.KeyF1: keybd_event.VK_F1, core.KeyF2: keybd_event.VK_F2, core.KeyF3: keybd_event.VK_F3,
+	core.KeyF4: keybd_event.VK_F4, core.KeyF5: keybd_event.VK_F5, core.KeyF6: keybd_event.VK_F6,
+	core.KeyF7: keybd_event.VK_F7, core.KeyF8: keybd_event.VK_F8, core.KeyF9: keybd_event.VK_F9,
+	core.KeyF10: keybd_event.VK_F10, core.KeyF11: keybd_event.VK_F11, core.KeyF12: keybd_event.VK_F12,
+	core.KeyF13: vkF13 + vkOffset, core.KeyF14: vkF13 + 1 + vkOffset, core.KeyF15: vkF13 + 2 + vkOffset,
+	core.KeyF16: vkF13 + 3 + vkOffset, core.KeyF17: vkF13 + 4 + vkOffset, core.KeyF18: vkF13 + 5 + vkOffset,
+	core.KeyF19: vkF13 + 6 + vkOffset, core.KeyF20: vkF13 + 7 + vkOffset, core.KeyF21: vkF13 + 8 + vkOffset,
+	core.KeyF22: vkF13 + 9 + vkOffset, core.KeyF23: vkF13 + 10 + vkOffset, core.KeyF24: vkF13 + 11 + vkOffset,
+
+	core.KeySpace: keybd_event.VK_SPACE,
+	core.KeyEnter: keybd_event.VK_ENTER,
+	core.KeyEsc:   keybd_event.VK_ESC,
+	core.KeyTab:   keybd_event.VK_TAB,
+	core.KeyUp:    keybd_event.VK_UP,
+	core.KeyDown:  keybd_event.VK_DOWN,
+	core.KeyLeft:  keybd_event.VK_LEFT,
+	core.KeyRight: keybd_event.VK_RIGHT,
+
+	core.KeyHome:        keybd_event.VK_HOME,
+	core.KeyEnd:         keybd_event.VK_END,
+	core.KeyPageUp:      keybd_event.VK_PAGEUP,
+	core.KeyPageDown:    keybd_event.VK_PAGEDOWN,
+	core.KeyInsert:      keybd_event.VK_INSERT,
+	core.KeyDelete:      keybd_event.VK_DELETE,
+	core.KeyBackspace:   keybd_event.VK_BACKSPACE,
+	core.KeyCapsLock:    keybd_event.VK_CAPSLOCK,
+	core.KeyNumLock:     vkNumLock + vkOffset,
+	core.KeyScrollLock:  keybd_event.VK_SCROLLLOCK,
+	core.KeyPrintScreen: keybd_event.VK_SNAPSHOT,
+	core.KeyPause:       keybd_event.VK_PAUSE,
+
+	// Numpad keys use virtual-key codes: their scan codes double as the
+	// navigation keys when Num Lock is off.
+	core.KeyNumpad0: vkNumpad0 + vkOffset, core.KeyNumpad1: vkNumpad0 + 1 + vkOffset,
+	core.KeyNumpad2: vkNumpad0 + 2 + vkOffset, core.KeyNumpad3: vkNumpad0 + 3 + vkOffset,
+	core.KeyNumpad4: vkNumpad0 + 4 + vkOffset, core.KeyNumpad5: vkNumpad0 + 5 + vkOffset,
+	core.KeyNumpad6: vkNumpad0 + 6 + vkOffset, core.KeyNumpad7: vkNumpad0 + 7 + vkOffset,
+	core.KeyNumpad8: vkNumpad0 + 8 + vkOffset, core.KeyNumpad9: vkNumpad0 + 9 + vkOffset,
+	core.KeyNumpadAdd:      vkAdd + vkOffset,
+	core.KeyNumpadSubtract: vkSubtract + vkOffset,
+	core.KeyNumpadMultiply: vkMultiply + vkOffset,
+	core.KeyNumpadDivide:   vkDivide + vkOffset,
+	core.KeyNumpadDecimal:  vkDecimal + vkOffset,
+	core.KeyNumpadEnter:    vkReturn + vkOffset,
+
+	core.KeyComma:        keybd_event.VK_OEM_COMMA,
+	core.KeyPeriod:       keybd_event.VK_OEM_PERIOD,
+	core.KeyMinus:        keybd_event.VK_OEM_MINUS,
+	core.KeyEqual:        keybd_event.VK_OEM_PLUS,
+	core.KeySlash:        keybd_event.VK_OEM_2,
+	core.KeyBackslash:    keybd_event.VK_OEM_5,
+	core.KeySemicolon:    keybd_event.VK_OEM_1,
+	core.KeyQuote:        keybd_event.VK_OEM_7,
+	core.KeyBackquote:    keybd_event.VK_OEM_3,
+	core.KeyLeftBracket:  keybd_event.VK_OEM_4,
+	core.KeyRightBracket: keybd_event.VK_OEM_6,
+
+	core.KeyVolumeUp:       keybd_event.VK_VOLUME_UP,
+	core.KeyVolumeDown:     keybd_event.VK_VOLUME_DOWN,
+	core.KeyVolumeMute:     keybd_event.VK_VOLUME_MUTE,
+	core.KeyMediaPlayPause: keybd_event.VK_MEDIA_PLAY_PAUSE,
+	core.KeyMediaNext:      keybd_event.VK_MEDIA_NEXT_TRACK,
+	core.KeyMediaPrev:      keybd_event.VK_MEDIA_PREV_TRACK,
+	core.KeyMediaStop:      keybd_event.VK_MEDIA_STOP,
+
+	core.KeyCtrl:  vkControl + vkOffset,
+	core.KeyShift: vkShift + vkOffset,
+	core.KeyAlt:   vkMenu + vkOffset,
+	core.KeyMeta:  vkLWin + vkOffset,
+}
+
+// windowsButtonFlags are the MOUSEEVENTF flags that press and release each
+// mouse button.
+var windowsButtonFlags = map[core.Key]struct{ down, up uint32 }{
+	core.KeyMouseLeft:   {0x0002, 0x0004},
+	core.KeyMouseRight:  {0x0008, 0x0010},
+	core.KeyMouseMiddle: {0x0020, 0x0040},
+}
+
+const (
+	inputMouse           = 0
+	inputKeyboard        = 1
+	keyeventfExtendedKey = 0x0001
+	keyeventfKeyUp       = 0x0002
+	keyeventfUnicode     = 0x0004
+	keyeventfScanCode    = 0x0008
+
+	vkOffset  = 0xFFF
+	vkShift   = 0x10
+	vkControl = 0x11
+	vkMenu    = 0x12
+	vkLWin    = 0x5B
+	vkReturn  = 0x0D
+	vkNumLock = 0x90
+	vkF13     = 0x7C
+
+	mouseeventfMove        = 0x0001
+	mouseeventfWheel       = 0x0800
+	mouseeventfVirtualDesk = 0x4000
+	mouseeventfAbsolute    = 0x8000
+	wheelDelta             = 120
+
+	smXVirtualScreen  = 76
+	smYVirtualScreen  = 77
+	smCXVirtualScreen = 78
+	smCYVirtualScreen = 79
+
+	vkNumpad0  = 0x60
+	vkMultiply = 0x6A
+	vkAdd      = 0x6B
+	vkSubtract = 0x6D
+	vkDecimal  = 0x6E
+	vkDivide   = 0x6F
+)
+
+// windowsExtendedKeys share a virtual-key code or scan code with another key
+// and need KEYEVENTF_EXTENDEDKEY to be told apart (arrows and the navigation
+// block versus the numpad, numpad Enter versus Enter).
+var windowsExtendedKeys = map[core.Key]bool{
+	core.KeyUp:           true,
+	core.KeyDown:         true,
+	core.KeyLeft:         true,
+	core.KeyRight:        true,
+	core.KeyHome:         true,
+	core.KeyEnd:          true,
+	core.KeyPageUp:       true,
+	core.KeyPageDown:     true,
+	core.KeyInsert:       true,
+	core.KeyDelete:       true,
+	core.KeyNumpadDivide: true,
+	core.KeyNumpadEnter:  true,
+	core.KeyMeta:         true,
+}
+
+type keyboardInput struct {
+	Vk        uint16
+	Scan      uint16
+	Flags     uint32
+	Time      uint32
+	ExtraInfo uintptr
+}
+
+type input struct {
+	Type uint32
+	Ki   keyboardInput
+	_    uint64
+}
+
+type mouseInput struct {
+	Dx        int32
+	Dy        int32
+	MouseData uint32
+	Flags     uint32
+	Time      uint32
+	ExtraInfo uintptr
+}
+
+// mouseEvent is an INPUT holding a MOUSEINPUT, the largest member of its
+// union, so it has the size of input.
+type mouseEvent struct {
+	Type uint32
+	Mi   mouseInput
+}
+
+var (
+	user32               = syscall.NewLazyDLL("user32.dll")
+	procSendInput        = user32.NewProc("SendInput")
+	procGetSystemMetrics = user32.NewProc("GetSystemMetrics")
+)
+
+func sendKey(key core.Key, up bool) error {
+	code, ok := windowsKeyCodes[key]
+	if !ok {
+		return fmt.Errorf("unsupported key code: %d", key)
+	}
+
+	ki := keyboardInput{}
+	if code < vkOffset {
+		ki.Scan = uint16(code)
+		ki.Flags = keyeventfScanCode
+	} else {
+		ki.Vk = uint16(code - vkOffset)
+	}
+	if windowsExtendedKeys[key] {
+		ki.Flags |= keyeventfExtendedKey
+	}
+	if up {
+		ki.Flags |= keyeventfKeyUp
+	}
+	return sendInput(ki)
+}
+
+// sendUnicode types r as VK_PACKET events. A character outside the BMP, such
+// as an emoji, is a surrogate pair: both halves go down before either comes
+// up, in one SendInput call, so the target reads them as one character.
+func sendUnicode(r rune) error {
+	units := utf16.Encode([]rune{r})
+	events := make([]keyboardInput, 0, 2*len(units))
+	for _, unit := range units {
+		events = append(events, keyboardInput{Scan: unit, Flags: keyeventfUnicode})
+	}
+	for _, unit := range units {
+		events = append(events, keyboardInput{Scan: unit, Flags: keyeventfUnicode | keyeventfKeyUp})
+	}
+	return sendInput(events...)
+}
+
+// sendInput reports an error when SendInput inserts fewer events than given.
+// That includes input blocked by UIPI, such as keys aimed at a window running
+// as administrator, although Windows does not give that as the reason.
+func sendInput(kis ...keyboardInput) error {
+	inputs := make([]input, len(kis))
+	for i, ki := range kis {
+		inputs[i] = input{Type: inputKeyboard, Ki: ki}
+	}
+	sent, _, err := procSendInput.Call(
+		uintptr(len(inputs)),
+		uintptr(unsafe.Pointer(&inputs[0])),
+		unsafe.Sizeof(inputs[0]),
+	)
+	if int(sent) != len(inputs) {
+		return fmt.Errorf("SendInput: %w", err)
+	}
+	return nil
+}
+
+func sendMouse(mi mouseInput) error {
+	event := mouseEvent{Type: inputMouse, Mi: mi}
+	sent, _, err := procSendInput.Call(1, uintptr(unsafe.Pointer(&event)), unsafe.Sizeof(event))
+	if sent != 1 {
+		return fmt.Errorf("SendInput: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package control
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"autokeypress/internal/profile"
+)
+
+// ErrNotServing is returned by a Client when nothing answers on its socket.
+var ErrNotServing = errors.New("no instance is serving the control API")
+
+// APIError is a request the instance refused.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// Client talks to the API of a running instance.
+type Client struct {
+	path string
+	http *http.Client
+}
+
+// NewClient returns a client for the socket at path, see SocketPath.
+func NewClient(path string) *Client {
+	return &Client{
+		path: path,
+		http: &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		}},
+	}
+}
+
+func (c *Client) Status() (Status, error) {
+	var status Status
+	err := c.do(http.MethodGet, "/status", nil, &status)
+	return status, err
+}
+
+func (c *Client) Add(entry profile.Entry) (EntryStatus, error) {
+	var added EntryStatus
+	err := c.do(http.MethodPost, "/entries", entry, &added)
+	return added, err
+}
+
+// Update replaces entry index, numbered from 1.
+func (c *Client) Update(index int, entry profile.Entry) (EntryStatus, error) {
+	var updated EntryStatus
+	err := c.do(http.MethodPut, "/entries/"+strconv.Itoa(index), entry, &updated)
+	return updated, err
+}
+
+// Remove removes entry index, numbered from 1.
+func (c *Client) Remove(index int) error {
+	return c.do(http.MethodDelete, "/entries/"+strconv.Itoa(index), nil, nil)
+}
+
+func (c *Client) Start() (Status, error) {
+	return c.post("/start")
+}
+
+func (c *Client) Stop() (Status, error) {
+	return c.post("/stop")
+}
+
+func (c *Client) Pause() (Status, error) {
+	return c.post("/pause")
+}
+
+func (c *Client) Resume() (Status, error) {
+	return c.post("/resume")
+}
+
+func (c *Client) post(path string) (Status, error) {
+	var status Status
+	err := c.do(http.MethodPost, path, nil, &status)
+	return status, err
+}
+
+// do sends body as JSON (if not nil) and decodes the response into out (if
+// not nil). Failed requests come back as *APIError.
+func (c *Client) do(method, path string, body, out any) error {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, "http://autokeypress"+path, &payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%w at %s: %v", ErrNotServing, c.path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var failure ErrorBody
+		if err := json.NewDecoder(resp.Body).Decode(&failure); err != nil || failure.Error == "" {
+			failure.Error = resp.Status
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: failure.Error}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,64 @@
+package control
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"autokeypress/internal/core"
+	"autokeypress/internal/profile"
+)
+
+func TestClient(t *testing.T) {
+	runner := core.NewRunner(nopInjector{})
+	path := filepath.Join(t.TempDir(), "control.sock")
+	server, err := Listen(path, &fakeController{runner: runner}, runner)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() {
+		runner.Stop()
+		server.Close()
+	})
+	client := NewClient(path)
+
+	added, err := client.Add(profile.Entry{Key: "A", IntervalMS: 10})
+	if err != nil || added.Index != 1 {
+		t.Fatalf("Add = %+v, %v, want entry 1", added, err)
+	}
+	if _, err := client.Update(1, profile.Entry{Key: "B", IntervalMS: 10}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	status, err := client.Start()
+	if err != nil || !status.Running || status.Entries[0].Key != "B" {
+		t.Fatalf("Start = %+v, %v, want B running", status, err)
+	}
+	if status, err = client.Pause(); err != nil || !status.Paused {
+		t.Errorf("Pause = %+v, %v, want paused", status, err)
+	}
+	if status, err = client.Resume(); err != nil || status.Paused {
+		t.Errorf("Resume = %+v, %v, want running", status, err)
+	}
+	if status, err = client.Stop(); err != nil || status.Running {
+		t.Errorf("Stop = %+v, %v, want stopped", status, err)
+	}
+	if err := client.Remove(1); err != nil {
+		t.Errorf("Remove: %v", err)
+	}
+
+	var apiErr *APIError
+	if err := client.Remove(1); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Remove of a missing entry = %v, want a 404 APIError", err)
+	}
+	if _, err := client.Start(); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("Start with no entries = %v, want a 409 APIError", err)
+	}
+}
+
+func TestClientNotServing(t *testing.T) {
+	client := NewClient(filepath.Join(t.TempDir(), "control.sock"))
+	if _, err := client.Status(); !errors.Is(err, ErrNotServing) {
+		t.Errorf("Status = %v, want ErrNotServing", err)
+	}
+}
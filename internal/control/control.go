@@ -0,0 +1,408 @@
+// Package control serves a local HTTP API on a Unix domain socket so scripts
+// and test harnesses can drive a running instance: list and edit its entries,
+// start, stop, pause and resume the keys and read their statistics. On Unix
+// the socket is only reachable by the user who started the app. Windows
+// ignores its permission bits, so there it is only as private as the folder it
+// is in; the default one, under the user's AppData, is.
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"autokeypress/internal/core"
+	"autokeypress/internal/profile"
+)
+
+// SocketEnv overrides where the socket is created and looked for.
+const SocketEnv = "AUTOKEYPRESS_SOCKET"
+
+const (
+	appDirName = "autokeypress"
+	socketName = "control.sock"
+)
+
+// ErrNotFound is returned by a Controller for an index outside its list.
+var ErrNotFound = errors.New("no such entry")
+
+// Controller is implemented by each UI on top of its own entry list and
+// buttons, so changes made through the API show up in the window the same
+// way as edits made there. Indexes are 0-based; the API numbers entries
+// from 1.
+type Controller interface {
+	// Entries returns the current list, copied with Snapshot where the UI
+	// edits it, as the caller reads it on its own goroutine.
+	Entries() []Listed
+	// AddEntry appends entry, starting it if the keys are running.
+	AddEntry(entry *core.KeyEntry) error
+	// UpdateEntry replaces the entry at index, restarting it if running.
+	UpdateEntry(index int, entry *core.KeyEntry) error
+	RemoveEntry(index int) error
+	// Start starts the enabled entries, honouring the start delay. skipped
+	// lists entries that could not be started; err is set when none could.
+	Start() (skipped []string, err error)
+	Stop()
+	Pause()
+	Resume()
+}
+
+// Listed is an entry of a Controller's list.
+type Listed struct {
+	// Entry is the list's own entry, which the runner's stats are keyed
+	// by. The UI may change it at any time, so it is only compared.
+	Entry *core.KeyEntry
+	// Copy holds Entry's fields as they were when the list was read.
+	Copy *core.KeyEntry
+}
+
+// Snapshot copies entries for Controller.Entries. It must be called where
+// the entries cannot change meanwhile.
+func Snapshot(entries []*core.KeyEntry) []Listed {
+	listed := make([]Listed, len(entries))
+	for i, entry := range entries {
+		listed[i] = Listed{Entry: entry, Copy: entry.Clone()}
+	}
+	return listed
+}
+
+// Status is the body of most responses.
+type Status struct {
+	Running bool          `json:"running"`
+	Paused  bool          `json:"paused"`
+	Entries []EntryStatus `json:"entries"`
+	// Skipped is only set by POST /start.
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// EntryStatus is an entry in the profile file layout with its statistics in
+// the current or most recent run.
+type EntryStatus struct {
+	Index int `json:"index"`
+	profile.Entry
+	Stats *Stats `json:"stats,omitempty"`
+}
+
+// Stats mirrors core.TaskStats.
+type Stats struct {
+	Presses        int        `json:"presses"`
+	Failures       int        `json:"failures"`
+	LastPress      *time.Time `json:"last_press,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	GaveUp         bool       `json:"gave_up,omitempty"`
+	MeanIntervalMS int64      `json:"mean_interval_ms,omitempty"`
+	DriftMS        int64      `json:"drift_ms,omitempty"`
+}
+
+// ErrorBody is the body of every failed request.
+type ErrorBody struct {
+	Error string `json:"error"`
+}
+
+// Server serves the API for one Controller.
+type Server struct {
+	path     string
+	listener net.Listener
+	http     *http.Server
+	ctl      Controller
+	runner   *core.Runner
+
+	// mu handles one request at a time so an edit never races another.
+	mu sync.Mutex
+}
+
+// SocketPath returns where the socket lives: $AUTOKEYPRESS_SOCKET, or
+// control.sock in the app's config directory, which is private to the user
+// on every platform.
+func SocketPath() (string, error) {
+	if path := os.Getenv(SocketEnv); path != "" {
+		return path, nil
+	}
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, appDirName, socketName), nil
+}
+
+// Listen starts serving on the socket at path. A socket left behind by an
+// instance that crashed is replaced, but one another instance still answers
+// on is not.
+func Listen(path string, ctl Controller, runner *core.Runner) (*Server, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("another instance is already serving %s", path)
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s is in the way and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// Windows does not apply these bits to sockets; see the package doc.
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	s := &Server{path: path, listener: listener, ctl: ctl, runner: runner}
+	s.http = &http.Server{Handler: s.handler()}
+	go s.http.Serve(listener)
+	return s, nil
+}
+
+// Close stops serving and removes the socket.
+func (s *Server) Close() error {
+	err := s.http.Close()
+	os.Remove(s.path)
+	return err
+}
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", s.serialized(s.getStatus))
+	mux.HandleFunc("GET /entries", s.serialized(s.getEntries))
+	mux.HandleFunc("POST /entries", s.serialized(s.addEntry))
+	mux.HandleFunc("GET /entries/{index}", s.serialized(s.getEntry))
+	mux.HandleFunc("PUT /entries/{index}", s.serialized(s.updateEntry))
+	mux.HandleFunc("DELETE /entries/{index}", s.serialized(s.removeEntry))
+	mux.HandleFunc("POST /start", s.serialized(s.start))
+	mux.HandleFunc("POST /stop", s.serialized(s.stop))
+	mux.HandleFunc("POST /pause", s.serialized(s.pause))
+	mux.HandleFunc("POST /resume", s.serialized(s.resume))
+	return mux
+}
+
+func (s *Server) serialized(handle http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		handle(w, r)
+	}
+}
+
+func (s *Server) getStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+func (s *Server) getEntries(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.status().Entries)
+}
+
+func (s *Server) getEntry(w http.ResponseWriter, r *http.Request) {
+	entries := s.ctl.Entries()
+	index, ok := s.index(w, r, len(entries))
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, newEntryStatus(index, entries[index], s.runner.Stats()))
+}
+
+func (s *Server) addEntry(w http.ResponseWriter, r *http.Request) {
+	entry, ok := readEntry(w, r)
+	if !ok {
+		return
+	}
+	// The UI may edit entry once it is in the list.
+	added := Listed{Entry: entry, Copy: entry.Clone()}
+	if err := s.ctl.AddEntry(entry); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	index := slices.IndexFunc(s.ctl.Entries(), func(l Listed) bool { return l.Entry == entry })
+	writeJSON(w, http.StatusCreated, newEntryStatus(index, added, s.runner.Stats()))
+}
+
+func (s *Server) updateEntry(w http.ResponseWriter, r *http.Request) {
+	index, ok := s.index(w, r, len(s.ctl.Entries()))
+	if !ok {
+		return
+	}
+	entry, ok := readEntry(w, r)
+	if !ok {
+		return
+	}
+	updated := Listed{Entry: entry, Copy: entry.Clone()}
+	if err := s.ctl.UpdateEntry(index, entry); err != nil {
+		writeControllerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newEntryStatus(index, updated, s.runner.Stats()))
+}
+
+func (s *Server) removeEntry(w http.ResponseWriter, r *http.Request) {
+	index, ok := s.index(w, r, len(s.ctl.Entries()))
+	if !ok {
+		return
+	}
+	if err := s.ctl.RemoveEntry(index); err != nil {
+		writeControllerError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) start(w http.ResponseWriter, r *http.Request) {
+	if s.runner.IsRunning() {
+		writeError(w, http.StatusConflict, errors.New("already running"))
+		return
+	}
+	skipped, err := s.ctl.Start()
+	if err != nil {
+		writeJSON(w, http.StatusConflict, struct {
+			ErrorBody
+			Skipped []string `json:"skipped,omitempty"`
+		}{ErrorBody{err.Error()}, skipped})
+		return
+	}
+	status := s.status()
+	status.Skipped = skipped
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) stop(w http.ResponseWriter, r *http.Request) {
+	s.ctl.Stop()
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+func (s *Server) pause(w http.ResponseWriter, r *http.Request) {
+	if !s.runner.IsRunning() {
+		writeError(w, http.StatusConflict, errors.New("not running"))
+		return
+	}
+	s.ctl.Pause()
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+func (s *Server) resume(w http.ResponseWriter, r *http.Request) {
+	if !s.runner.IsPaused() {
+		writeError(w, http.StatusConflict, errors.New("not paused"))
+		return
+	}
+	s.ctl.Resume()
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+func (s *Server) status() Status {
+	stats := s.runner.Stats()
+	status := Status{
+		Running: s.runner.IsRunning(),
+		Paused:  s.runner.IsPaused(),
+		Entries: []EntryStatus{},
+	}
+	for i, listed := range s.ctl.Entries() {
+		status.Entries = append(status.Entries, newEntryStatus(i, listed, stats))
+	}
+	return status
+}
+
+// newEntryStatus describes listed, found at the 0-based index.
+func newEntryStatus(index int, listed Listed, stats map[*core.KeyEntry]core.TaskStats) EntryStatus {
+	status := EntryStatus{Index: index + 1, Entry: profile.NewEntry(listed.Copy)}
+	if taskStats, ok := stats[listed.Entry]; ok {
+		status.Stats = newStats(taskStats)
+	}
+	return status
+}
+
+// index parses the 1-based {index} path value into a 0-based index into a
+// list of count entries, answering 404 when there is no such entry. The
+// controller still checks the index, as the list may change before it is
+// used.
+func (s *Server) index(w http.ResponseWriter, r *http.Request, count int) (int, bool) {
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil || index < 1 || index > count {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", ErrNotFound, r.PathValue("index")))
+		return 0, false
+	}
+	return index - 1, true
+}
+
+// readEntry decodes and checks the entry in the request body. Whether the
+// platform can send the key is left to the Controller.
+func readEntry(w http.ResponseWriter, r *http.Request) (*core.KeyEntry, bool) {
+	var body profile.Entry
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid entry: %w", err))
+		return nil, false
+	}
+	entry, err := body.KeyEntry()
+	if err == nil {
+		err = checkEntry(entry)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+	return entry, true
+}
+
+func checkEntry(entry *core.KeyEntry) error {
+	if entry.IntervalMS <= 0 {
+		return errors.New("interval_ms must be positive")
+	}
+	if !entry.IsSequence() && !entry.IsText() && !core.ValidEntry(entry.Key, entry.IntervalMS) {
+		return errors.New("key is required")
+	}
+	enabled := *entry
+	enabled.Enabled = true
+	if _, errs := core.BuildTasks([]*core.KeyEntry{&enabled}, nil); len(errs) > 0 {
+		return errors.New(errs[0])
+	}
+	return nil
+}
+
+func newStats(s core.TaskStats) *Stats {
+	stats := &Stats{
+		Presses:        s.Presses,
+		Failures:       s.Failures,
+		GaveUp:         s.GaveUp,
+		MeanIntervalMS: s.MeanInterval().Milliseconds(),
+		DriftMS:        s.Drift().Milliseconds(),
+	}
+	if !s.LastPress.IsZero() {
+		last := s.LastPress
+		stats.LastPress = &last
+	}
+	if s.LastError != nil {
+		stats.LastError = s.LastError.Error()
+	}
+	return stats
+}
+
+func writeControllerError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeError(w, http.StatusBadRequest, err)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, ErrorBody{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,308 @@
+package control
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"autokeypress/internal/core"
+)
+
+type nopInjector struct{}
+
+func (nopInjector) Supports(key core.Key) bool     { return key != core.KeyF24 }
+func (nopInjector) KeyDown(core.Key) error         { return nil }
+func (nopInjector) KeyUp(core.Key) error           { return nil }
+func (nopInjector) TypeRune(rune) error            { return nil }
+func (nopInjector) MoveMouse(int, int, bool) error { return nil }
+func (nopInjector) Scroll(int) error               { return nil }
+
+// fakeController is the entry list a UI would keep, without the widgets.
+type fakeController struct {
+	mu      sync.Mutex
+	runner  *core.Runner
+	entries []*core.KeyEntry
+}
+
+func (c *fakeController) Entries() []Listed {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Snapshot(c.entries)
+}
+
+func (c *fakeController) list() []*core.KeyEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.entries)
+}
+
+func (c *fakeController) AddEntry(entry *core.KeyEntry) error {
+	c.mu.Lock()
+	c.entries = append(c.entries, entry)
+	c.mu.Unlock()
+	return c.runner.UpdateEntry(nil, entry)
+}
+
+func (c *fakeController) UpdateEntry(index int, entry *core.KeyEntry) error {
+	c.mu.Lock()
+	if index >= len(c.entries) {
+		c.mu.Unlock()
+		return ErrNotFound
+	}
+	old := c.entries[index]
+	c.entries[index] = entry
+	c.mu.Unlock()
+	return c.runner.UpdateEntry(old, entry)
+}
+
+func (c *fakeController) RemoveEntry(index int) error {
+	c.mu.Lock()
+	if index >= len(c.entries) {
+		c.mu.Unlock()
+		return ErrNotFound
+	}
+	removed := c.entries[index]
+	c.entries = append(c.entries[:index], c.entries[index+1:]...)
+	c.mu.Unlock()
+	c.runner.RemoveEntry(removed)
+	return nil
+}
+
+func (c *fakeController) Start() ([]string, error) {
+	tasks, skipped := core.BuildTasks(c.list(), nopInjector{})
+	if len(tasks) == 0 {
+		return skipped, errors.New("no enabled keys")
+	}
+	c.runner.Start(tasks)
+	return skipped, nil
+}
+
+func (c *fakeController) Stop()   { c.runner.Stop() }
+func (c *fakeController) Pause()  { c.runner.Pause() }
+func (c *fakeController) Resume() { c.runner.Resume() }
+
+func serve(t *testing.T) (*fakeController, *http.Client) {
+	t.Helper()
+	runner := core.NewRunner(nopInjector{})
+	ctl := &fakeController{runner: runner}
+	path := filepath.Join(t.TempDir(), "control.sock")
+	server, err := Listen(path, ctl, runner)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() {
+		runner.Stop()
+		server.Close()
+	})
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	return ctl, client
+}
+
+// call sends body (if any) and decodes the response into out (if any),
+// returning the status code.
+func call(t *testing.T, client *http.Client, method, path, body string, out any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, "http://autokeypress"+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		var buf bytes.Buffer
+		buf.ReadFrom(resp.Body)
+		if err := json.Unmarshal(buf.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decoding %q: %v", method, path, buf.String(), err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestServerEntries(t *testing.T) {
+	ctl, client := serve(t)
+
+	var added EntryStatus
+	if code := call(t, client, "POST", "/entries", `{"key":"A","interval_ms":500}`, &added); code != http.StatusCreated {
+		t.Fatalf("POST /entries = %d, want %d", code, http.StatusCreated)
+	}
+	if added.Index != 1 || added.Key != "A" || added.IntervalMS != 500 || !*added.Enabled {
+		t.Errorf("added = %+v, want entry 1, A every 500 ms, enabled", added)
+	}
+	call(t, client, "POST", "/entries", `{"key":"B","interval_ms":1000,"enabled":false}`, nil)
+
+	var updated EntryStatus
+	if code := call(t, client, "PUT", "/entries/2", `{"key":"CTRL+C","interval_ms":250}`, &updated); code != http.StatusOK {
+		t.Fatalf("PUT /entries/2 = %d, want %d", code, http.StatusOK)
+	}
+	if updated.Index != 2 || updated.Key != "CTRL+C" {
+		t.Errorf("updated = %+v, want entry 2 CTRL+C", updated)
+	}
+	if code := call(t, client, "DELETE", "/entries/1", "", nil); code != http.StatusNoContent {
+		t.Fatalf("DELETE /entries/1 = %d, want %d", code, http.StatusNoContent)
+	}
+
+	var entries []EntryStatus
+	call(t, client, "GET", "/entries", "", &entries)
+	if len(entries) != 1 || entries[0].Index != 1 || entries[0].Key != "CTRL+C" {
+		t.Errorf("GET /entries = %+v, want only CTRL+C as entry 1", entries)
+	}
+	if got := ctl.list(); len(got) != 1 || got[0].Key != "CTRL+C" {
+		t.Errorf("controller entries = %v, want the API's changes", got)
+	}
+}
+
+func TestServerTextEntries(t *testing.T) {
+	_, client := serve(t)
+	file := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(file, []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	bodies := []string{
+		`{"text":"hello {{.N}}","interval_ms":500}`,
+		fmt.Sprintf(`{"text_file":%q,"interval_ms":500}`, file),
+	}
+	for _, body := range bodies {
+		var added EntryStatus
+		code := call(t, client, "POST", "/entries", body, &added)
+		if code != http.StatusCreated || added.Key != "" || added.Text+added.TextFile == "" {
+			t.Errorf("POST /entries %s = %d %+v, want an unnamed text entry", body, code, added)
+		}
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	_, client := serve(t)
+	call(t, client, "POST", "/entries", `{"key":"A","interval_ms":500}`, nil)
+
+	tests := []struct {
+		method, path, body string
+		want               int
+		wantErr            string
+	}{
+		{"GET", "/entries/2", "", http.StatusNotFound, "no such entry: 2"},
+		{"DELETE", "/entries/x", "", http.StatusNotFound, "no such entry: x"},
+		{"POST", "/entries", `{"key":"A"}`, http.StatusBadRequest, "interval_ms must be positive"},
+		{"POST", "/entries", `{"key":"NOPE","interval_ms":100}`, http.StatusBadRequest, "NOPE"},
+		{"POST", "/entries", `{"key":"A","interval":100}`, http.StatusBadRequest, "unknown field"},
+		{"PUT", "/entries/1", `{"key":"A","interval_ms":1,"jitter_distribution":"normal"}`, http.StatusBadRequest, "unknown jitter_distribution"},
+		{"POST", "/pause", "", http.StatusConflict, "not running"},
+		{"POST", "/resume", "", http.StatusConflict, "not paused"},
+	}
+	for _, tt := range tests {
+		var body ErrorBody
+		code := call(t, client, tt.method, tt.path, tt.body, &body)
+		if code != tt.want || !strings.Contains(body.Error, tt.wantErr) {
+			t.Errorf("%s %s = %d %q, want %d containing %q", tt.method, tt.path, code, body.Error, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestServerStartPauseStop(t *testing.T) {
+	ctl, client := serve(t)
+	call(t, client, "POST", "/entries", `{"key":"A","interval_ms":10}`, nil)
+	call(t, client, "POST", "/entries", `{"key":"F24","interval_ms":10}`, nil)
+
+	var status Status
+	if code := call(t, client, "POST", "/start", "", &status); code != http.StatusOK {
+		t.Fatalf("POST /start = %d, want %d", code, http.StatusOK)
+	}
+	if !status.Running || len(status.Skipped) != 1 {
+		t.Errorf("start status = %+v, want running with F24 skipped", status)
+	}
+	if code := call(t, client, "POST", "/start", "", nil); code != http.StatusConflict {
+		t.Errorf("second POST /start = %d, want %d", code, http.StatusConflict)
+	}
+
+	waitFor(t, "presses", func() bool {
+		call(t, client, "GET", "/status", "", &status)
+		return status.Entries[0].Stats != nil && status.Entries[0].Stats.Presses >= 2
+	})
+	if status.Entries[0].Stats.LastPress == nil {
+		t.Errorf("stats = %+v, want a last press time", status.Entries[0].Stats)
+	}
+
+	call(t, client, "POST", "/pause", "", &status)
+	if !status.Paused || !ctl.runner.IsPaused() {
+		t.Errorf("pause status = %+v, want paused", status)
+	}
+	call(t, client, "POST", "/resume", "", &status)
+	if status.Paused {
+		t.Errorf("resume status = %+v, want running", status)
+	}
+	call(t, client, "POST", "/stop", "", &status)
+	if status.Running || ctl.runner.IsRunning() {
+		t.Errorf("stop status = %+v, want stopped", status)
+	}
+}
+
+func TestListenSocketInUse(t *testing.T) {
+	runner := core.NewRunner(nopInjector{})
+	path := filepath.Join(t.TempDir(), "control.sock")
+	first, err := Listen(path, &fakeController{runner: runner}, runner)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	if _, err := Listen(path, &fakeController{runner: runner}, runner); err == nil {
+		t.Error("second Listen on a served socket succeeded")
+	}
+
+	first.Close()
+
+	// A socket file nobody answers on, as left by a crash, is replaced.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	second, err := Listen(path, &fakeController{runner: runner}, runner)
+	if err != nil {
+		t.Fatalf("Listen over a stale socket: %v", err)
+	}
+	second.Close()
+}
+
+func TestListenKeepsOtherFiles(t *testing.T) {
+	runner := core.NewRunner(nopInjector{})
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("keep me"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if server, err := Listen(path, &fakeController{runner: runner}, runner); err == nil {
+		server.Close()
+		t.Fatal("Listen replaced a regular file")
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "keep me" {
+		t.Errorf("file after Listen = %q, %v; want it untouched", data, err)
+	}
+}
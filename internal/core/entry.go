@@ -0,0 +1,186 @@
+// Package core holds the model, key parser and scheduler shared by every
+// platform build. It has no OS or UI dependencies; key presses go through an
+// Injector supplied by the platform code.
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type KeyEntry struct {
+	Key        string
+	IntervalMS int
+	Enabled    bool
+	// OffsetMS shifts the entry's presses later by a fixed amount so entries
+	// sharing an interval do not press at the same instant.
+	OffsetMS int
+
+	// JitterMS or JitterPercent (of IntervalMS) randomly shortens or
+	// lengthens each interval by up to that much; JitterMS wins if both are
+	// set. JitterDistribution is DistributionUniform (the default) or
+	// DistributionGaussian.
+	JitterMS           int
+	JitterPercent      int
+	JitterDistribution string
+	// HoldMinMS and HoldMaxMS bound a random time the key is held down.
+	HoldMinMS int
+	HoldMaxMS int
+
+	// Steps makes the entry a sequence: every interval the steps run in
+	// order and Key is only the sequence's name.
+	Steps []Step
+
+	// Text or TextFile makes the entry type text every interval, Key being
+	// only its name. Text takes \n, \t and \\ escapes and is a
+	// text/template (see TextData); TextFile is read on every run and typed
+	// as it is. TypeDelayMS waits between typed characters, for apps that
+	// drop fast input, here and in the type steps of a sequence.
+	Text        string
+	TextFile    string
+	TypeDelayMS int
+
+	// MaxPresses, MaxDurationMS and StopAt (HH:MM, local time) end the
+	// entry on its own; zero values mean it runs until stopped. A sequence
+	// counts one press per run.
+	MaxPresses    int
+	MaxDurationMS int
+	StopAt        string
+}
+
+// IsSequence reports whether the entry runs Steps rather than pressing Key.
+func (e *KeyEntry) IsSequence() bool {
+	return len(e.Steps) > 0
+}
+
+// IsText reports whether the entry types Text or TextFile rather than
+// pressing Key. Steps take precedence.
+func (e *KeyEntry) IsText() bool {
+	return !e.IsSequence() && (e.Text != "" || e.TextFile != "")
+}
+
+// Clone returns a copy of the entry that shares no steps with it.
+func (e *KeyEntry) Clone() *KeyEntry {
+	clone := *e
+	clone.Steps = slices.Clone(e.Steps)
+	return &clone
+}
+
+// Active reports whether the entry should be scheduled when the runner starts.
+func (e *KeyEntry) Active() bool {
+	return e.Enabled && e.IntervalMS > 0 && (e.IsSequence() || e.IsText() || strings.TrimSpace(e.Key) != "")
+}
+
+// DisplayKey is the key column of the lists: the key itself, the name and
+// length of a sequence, or the name or start of a text entry's text.
+func (e *KeyEntry) DisplayKey() string {
+	if e.IsText() {
+		return e.displayText()
+	}
+	if !e.IsSequence() {
+		return e.Key
+	}
+	name := strings.TrimSpace(e.Key)
+	if name == "" {
+		name = "Sequence"
+	}
+	if len(e.Steps) == 1 {
+		return name + " (1 step)"
+	}
+	return fmt.Sprintf("%s (%d steps)", name, len(e.Steps))
+}
+
+// displayTextRunes is how much of an unnamed text entry's text is shown.
+const displayTextRunes = 20
+
+func (e *KeyEntry) displayText() string {
+	if name := strings.TrimSpace(e.Key); name != "" {
+		return name + " (text)"
+	}
+	if e.TextFile != "" {
+		return "type " + filepath.Base(e.TextFile)
+	}
+	text := []rune(e.Text)
+	if len(text) > displayTextRunes {
+		return "type " + strconv.Quote(string(text[:displayTextRunes])) + "…"
+	}
+	return "type " + strconv.Quote(e.Text)
+}
+
+// ValidEntry checks the fields the add dialogs ask for.
+func ValidEntry(key string, intervalMS int) bool {
+	return strings.TrimSpace(key) != "" && intervalMS > 0
+}
+
+// BuildTasks turns the active entries into runnable tasks. Entries whose key
+// cannot be parsed, or that injector cannot send, are skipped and reported in
+// errors. A nil injector skips the platform check.
+func BuildTasks(entries []*KeyEntry, injector Injector) (tasks []KeyTask, errors []string) {
+	for _, entry := range entries {
+		if !entry.Active() {
+			continue
+		}
+		var (
+			task KeyTask
+			err  error
+		)
+		switch {
+		case entry.IsSequence():
+			task.Steps, err = buildSteps(entry.Steps, injector)
+			if err != nil {
+				err = fmt.Errorf("%s: %w", entry.DisplayKey(), err)
+			}
+		case entry.IsText():
+			task.Text, err = buildText(entry)
+			if err != nil {
+				err = fmt.Errorf("%s: %w", entry.DisplayKey(), err)
+			}
+		default:
+			task, err = ParseKey(entry.Key)
+			if err == nil && injector != nil {
+				err = checkSupported(task, injector)
+			}
+		}
+		if err != nil {
+			errors = append(errors, err.Error())
+			continue
+		}
+		task.Interval = time.Duration(entry.IntervalMS) * time.Millisecond
+		task.Offset = time.Duration(entry.OffsetMS) * time.Millisecond
+		task.TypeDelay = time.Duration(entry.TypeDelayMS) * time.Millisecond
+		task.Timing = entryTiming(entry)
+		task.Limits = entryLimits(entry)
+		task.Entry = entry
+		tasks = append(tasks, task)
+	}
+	return tasks, errors
+}
+
+func ParseInterval(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	case string:
+		value := strings.TrimSpace(v)
+		if value == "" {
+			return 0
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return 0
+		}
+		return parsed
+	default:
+		return 0
+	}
+}
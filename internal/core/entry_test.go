@@ -0,0 +1,114 @@
+package core
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBuildTasks(t *testing.T) {
+	entries := []*KeyEntry{
+		{Key: "A", IntervalMS: 100, Enabled: true},
+		{Key: "F5", IntervalMS: 250, Enabled: false},
+		{Key: "", IntervalMS: 100, Enabled: true},
+		{Key: "SPACE", IntervalMS: 0, Enabled: true},
+		{Key: "hello", IntervalMS: 100, Enabled: true},
+		{Key: "TAB", IntervalMS: 1500, Enabled: true, JitterMS: 50, HoldMinMS: 10},
+		{Key: "CTRL+VOLUMEUP", IntervalMS: 100, Enabled: true},
+	}
+	injector := newFakeInjector()
+	injector.unsupported = map[Key]bool{KeyVolumeUp: true}
+	tasks, errs := BuildTasks(entries, injector)
+	if len(tasks) != 2 {
+		t.Fatalf("BuildTasks built %d tasks, want 2: %+v", len(tasks), tasks)
+	}
+	if tasks[0].UnicodeRune != 'A' || tasks[0].Interval != 100*time.Millisecond {
+		t.Errorf("first task = %+v", tasks[0])
+	}
+	if tasks[1].Key != KeyTab || tasks[1].Interval != 1500*time.Millisecond ||
+		tasks[1].Timing.Jitter != 50*time.Millisecond || tasks[1].Timing.HoldMin != 10*time.Millisecond {
+		t.Errorf("second task = %+v", tasks[1])
+	}
+	want := []string{"unsupported key: hello", "VOLUMEUP is not supported on this platform"}
+	if !slices.Equal(errs, want) {
+		t.Errorf("errors = %q, want %q", errs, want)
+	}
+}
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		value any
+		want  int
+	}{
+		{value: 250, want: 250},
+		{value: int32(10), want: 10},
+		{value: int64(20), want: 20},
+		{value: 1.9, want: 1},
+		{value: " 500 ", want: 500},
+		{value: "", want: 0},
+		{value: "soon", want: 0},
+		{value: nil, want: 0},
+	}
+	for _, tt := range tests {
+		if got := ParseInterval(tt.value); got != tt.want {
+			t.Errorf("ParseInterval(%#v) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayKey(t *testing.T) {
+	oneStep := []Step{{Kind: StepKey, Key: "F5"}}
+	twoSteps := []Step{{Kind: StepKey, Key: "F5"}, {Kind: StepWait, WaitMS: 10}}
+	tests := []struct {
+		entry KeyEntry
+		want  string
+	}{
+		{entry: KeyEntry{Key: "CTRL+C"}, want: "CTRL+C"},
+		{entry: KeyEntry{Key: "Login", Steps: twoSteps}, want: "Login (2 steps)"},
+		{entry: KeyEntry{Steps: oneStep}, want: "Sequence (1 step)"},
+		{entry: KeyEntry{Key: "Greeting", Text: "hello"}, want: "Greeting (text)"},
+		{entry: KeyEntry{Text: "hello"}, want: `type "hello"`},
+		{entry: KeyEntry{Text: "the quick brown fox jumps"}, want: `type "the quick brown fox "…`},
+		{entry: KeyEntry{TextFile: "/tmp/notes.txt"}, want: "type notes.txt"},
+	}
+	for _, tt := range tests {
+		if got := tt.entry.DisplayKey(); got != tt.want {
+			t.Errorf("DisplayKey(%+v) = %q, want %q", tt.entry, got, tt.want)
+		}
+	}
+}
+
+func TestBuildTasksSequences(t *testing.T) {
+	entries := []*KeyEntry{
+		{IntervalMS: 100, Enabled: true, Steps: []Step{{Kind: StepKey, Key: "CTRL+A"}, {Kind: StepWait, WaitMS: 20}, {Kind: StepType, Text: "hi"}}},
+		{Key: "Broken", IntervalMS: 100, Enabled: true, Steps: []Step{{Kind: StepWait, WaitMS: 5}, {Kind: StepKey, Key: "hello"}}},
+	}
+	tasks, errs := BuildTasks(entries, newFakeInjector())
+	if len(tasks) != 1 {
+		t.Fatalf("BuildTasks built %d tasks, want 1", len(tasks))
+	}
+	want := []TaskStep{
+		{Kind: StepKey, Press: KeyTask{Key: KeyA, Modifiers: []Key{KeyCtrl}}},
+		{Kind: StepWait, Wait: 20 * time.Millisecond},
+		{Kind: StepType, Text: "hi"},
+	}
+	if !reflect.DeepEqual(tasks[0].Steps, want) {
+		t.Errorf("steps = %+v, want %+v", tasks[0].Steps, want)
+	}
+	if wantErr := []string{"Broken (2 steps): step 2: unsupported key: hello"}; !slices.Equal(errs, wantErr) {
+		t.Errorf("errors = %q, want %q", errs, wantErr)
+	}
+}
+
+func TestKeyEntryClone(t *testing.T) {
+	entry := &KeyEntry{Key: "copy", IntervalMS: 500, Enabled: true, Steps: []Step{{Kind: StepKey, Key: "CTRL+C"}}}
+	clone := entry.Clone()
+	if clone == entry || !reflect.DeepEqual(clone, entry) {
+		t.Fatalf("Clone = %+v, want an equal copy of %+v", clone, entry)
+	}
+	clone.Steps[0].Key = "CTRL+V"
+	if entry.Steps[0].Key != "CTRL+C" {
+		t.Error("editing the clone's steps changed the original")
+	}
+}
@@ -0,0 +1,120 @@
+package core
+
+import "slices"
+
+// heldKeys are the keys one sequence run has put down with down and hold
+// steps, in the order they went down. The run releases them when it ends,
+// however it ends, and while it is paused.
+type heldKeys struct {
+	keys []Key
+}
+
+// keysOf lists a press's keys in the order they go down.
+func keysOf(task KeyTask) []Key {
+	return append(slices.Clone(task.Modifiers), task.Key)
+}
+
+// keyDown presses task's modifiers and key and leaves them held. Keys the run
+// already holds are not pressed again.
+func (r *Runner) keyDown(held *heldKeys, task KeyTask) error {
+	r.pressMu.Lock()
+	defer r.pressMu.Unlock()
+	for _, key := range keysOf(task) {
+		if slices.Contains(held.keys, key) {
+			continue
+		}
+		if err := r.injector.KeyDown(key); err != nil {
+			return err
+		}
+		held.keys = append(held.keys, key)
+		r.held[key]++
+	}
+	return nil
+}
+
+// keyUp releases task's key and then its modifiers, whether or not the run
+// holds them.
+func (r *Runner) keyUp(held *heldKeys, task KeyTask) error {
+	r.pressMu.Lock()
+	defer r.pressMu.Unlock()
+	keys := keysOf(task)
+	var firstErr error
+	for i := len(keys) - 1; i >= 0; i-- {
+		if err := r.injector.KeyUp(keys[i]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if index := slices.Index(held.keys, keys[i]); index >= 0 {
+			held.keys = slices.Delete(held.keys, index, index+1)
+			r.unhold(keys[i])
+		}
+	}
+	return firstErr
+}
+
+// releaseHeld lets go of every key the run holds, last pressed first. With
+// keep the run still counts them as held, so pressHeld can put them back
+// down on Resume.
+func (r *Runner) releaseHeld(held *heldKeys, keep bool) error {
+	if held == nil {
+		return nil
+	}
+	r.pressMu.Lock()
+	defer r.pressMu.Unlock()
+	var firstErr error
+	for i := len(held.keys) - 1; i >= 0; i-- {
+		if err := r.injector.KeyUp(held.keys[i]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		r.unhold(held.keys[i])
+	}
+	if !keep {
+		held.keys = nil
+	}
+	return firstErr
+}
+
+// pressHeld puts the keys releaseHeld kept back down.
+func (r *Runner) pressHeld(held *heldKeys) error {
+	if held == nil {
+		return nil
+	}
+	r.pressMu.Lock()
+	defer r.pressMu.Unlock()
+	for _, key := range held.keys {
+		if err := r.injector.KeyDown(key); err != nil {
+			return err
+		}
+		r.held[key]++
+	}
+	return nil
+}
+
+// unhold is called with pressMu held.
+func (r *Runner) unhold(key Key) {
+	if r.held[key]--; r.held[key] <= 0 {
+		delete(r.held, key)
+	}
+}
+
+// releaseAll lets go of the keys every task holds. A panicking task calls it
+// so the crash leaves no key stuck down in other apps.
+func (r *Runner) releaseAll() {
+	r.pressMu.Lock()
+	defer r.pressMu.Unlock()
+	for key := range r.held {
+		r.injector.KeyUp(key)
+		delete(r.held, key)
+	}
+}
+
+// withoutHeld drops the modifiers the run holds from a press, so pressing
+// SHIFT+A while SHIFT is held down does not release it.
+func withoutHeld(task KeyTask, held *heldKeys) KeyTask {
+	if len(held.keys) == 0 || len(task.Modifiers) == 0 {
+		return task
+	}
+	task.Modifiers = slices.DeleteFunc(slices.Clone(task.Modifiers), func(key Key) bool {
+		return slices.Contains(held.keys, key)
+	})
+	return task
+}
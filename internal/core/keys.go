@@ -0,0 +1,408 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Key identifies a physical key independently of the platform. Each
+// Injector maps it to its native key code.
+type Key int
+
+const (
+	KeyNone Key = iota
+	KeyA
+	KeyB
+	KeyC
+	KeyD
+	KeyE
+	KeyF
+	KeyG
+	KeyH
+	KeyI
+	KeyJ
+	KeyK
+	KeyL
+	KeyM
+	KeyN
+	KeyO
+	KeyP
+	KeyQ
+	KeyR
+	KeyS
+	KeyT
+	KeyU
+	KeyV
+	KeyW
+	KeyX
+	KeyY
+	KeyZ
+	Key0
+	Key1
+	Key2
+	Key3
+	Key4
+	Key5
+	Key6
+	Key7
+	Key8
+	Key9
+	KeyF1
+	KeyF2
+	KeyF3
+	KeyF4
+	KeyF5
+	KeyF6
+	KeyF7
+	KeyF8
+	KeyF9
+	KeyF10
+	KeyF11
+	KeyF12
+	KeyF13
+	KeyF14
+	KeyF15
+	KeyF16
+	KeyF17
+	KeyF18
+	KeyF19
+	KeyF20
+	KeyF21
+	KeyF22
+	KeyF23
+	KeyF24
+	KeySpace
+	KeyEnter
+	KeyEsc
+	KeyTab
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+	KeyInsert
+	KeyDelete
+	KeyBackspace
+	KeyCapsLock
+	KeyNumLock
+	KeyScrollLock
+	KeyPrintScreen
+	KeyPause
+	KeyNumpad0
+	KeyNumpad1
+	KeyNumpad2
+	KeyNumpad3
+	KeyNumpad4
+	KeyNumpad5
+	KeyNumpad6
+	KeyNumpad7
+	KeyNumpad8
+	KeyNumpad9
+	KeyNumpadAdd
+	KeyNumpadSubtract
+	KeyNumpadMultiply
+	KeyNumpadDivide
+	KeyNumpadDecimal
+	KeyNumpadEnter
+	KeyComma
+	KeyPeriod
+	KeyMinus
+	KeyEqual
+	KeySlash
+	KeyBackslash
+	KeySemicolon
+	KeyQuote
+	KeyBackquote
+	KeyLeftBracket
+	KeyRightBracket
+	KeyVolumeUp
+	KeyVolumeDown
+	KeyVolumeMute
+	KeyMediaPlayPause
+	KeyMediaNext
+	KeyMediaPrev
+	KeyMediaStop
+	KeyMouseLeft
+	KeyMouseRight
+	KeyMouseMiddle
+	KeyCtrl
+	KeyShift
+	KeyAlt
+	KeyMeta
+)
+
+// keyTable is the registry of every supported key. The first name is the
+// canonical one used when printing a key; the others are accepted aliases.
+var keyTable = []struct {
+	key   Key
+	names []string
+}{
+	{KeyA, []string{"A"}},
+	{KeyB, []string{"B"}},
+	{KeyC, []string{"C"}},
+	{KeyD, []string{"D"}},
+	{KeyE, []string{"E"}},
+	{KeyF, []string{"F"}},
+	{KeyG, []string{"G"}},
+	{KeyH, []string{"H"}},
+	{KeyI, []string{"I"}},
+	{KeyJ, []string{"J"}},
+	{KeyK, []string{"K"}},
+	{KeyL, []string{"L"}},
+	{KeyM, []string{"M"}},
+	{KeyN, []string{"N"}},
+	{KeyO, []string{"O"}},
+	{KeyP, []string{"P"}},
+	{KeyQ, []string{"Q"}},
+	{KeyR, []string{"R"}},
+	{KeyS, []string{"S"}},
+	{KeyT, []string{"T"}},
+	{KeyU, []string{"U"}},
+	{KeyV, []string{"V"}},
+	{KeyW, []string{"W"}},
+	{KeyX, []string{"X"}},
+	{KeyY, []string{"Y"}},
+	{KeyZ, []string{"Z"}},
+
+	{Key0, []string{"0"}},
+	{Key1, []string{"1"}},
+	{Key2, []string{"2"}},
+	{Key3, []string{"3"}},
+	{Key4, []string{"4"}},
+	{Key5, []string{"5"}},
+	{Key6, []string{"6"}},
+	{Key7, []string{"7"}},
+	{Key8, []string{"8"}},
+	{Key9, []string{"9"}},
+
+	{KeyF1, []string{"F1"}},
+	{KeyF2, []string{"F2"}},
+	{KeyF3, []string{"F3"}},
+	{KeyF4, []string{"F4"}},
+	{KeyF5, []string{"F5"}},
+	{KeyF6, []string{"F6"}},
+	{KeyF7, []string{"F7"}},
+	{KeyF8, []string{"F8"}},
+	{KeyF9, []string{"F9"}},
+	{KeyF10, []string{"F10"}},
+	{KeyF11, []string{"F11"}},
+	{KeyF12, []string{"F12"}},
+	{KeyF13, []string{"F13"}},
+	{KeyF14, []string{"F14"}},
+	{KeyF15, []string{"F15"}},
+	{KeyF16, []string{"F16"}},
+	{KeyF17, []string{"F17"}},
+	{KeyF18, []string{"F18"}},
+	{KeyF19, []string{"F19"}},
+	{KeyF20, []string{"F20"}},
+	{KeyF21, []string{"F21"}},
+	{KeyF22, []string{"F22"}},
+	{KeyF23, []string{"F23"}},
+	{KeyF24, []string{"F24"}},
+
+	{KeySpace, []string{"SPACE"}},
+	{KeyEnter, []string{"ENTER", "RETURN"}},
+	{KeyEsc, []string{"ESC", "ESCAPE"}},
+	{KeyTab, []string{"TAB"}},
+	{KeyUp, []string{"UP"}},
+	{KeyDown, []string{"DOWN"}},
+	{KeyLeft, []string{"LEFT"}},
+	{KeyRight, []string{"RIGHT"}},
+
+	{KeyHome, []string{"HOME"}},
+	{KeyEnd, []string{"END"}},
+	{KeyPageUp, []string{"PAGEUP", "PGUP"}},
+	{KeyPageDown, []string{"PAGEDOWN", "PGDN"}},
+	{KeyInsert, []string{"INSERT", "INS"}},
+	{KeyDelete, []string{"DELETE", "DEL"}},
+	{KeyBackspace, []string{"BACKSPACE", "BKSP"}},
+	{KeyCapsLock, []string{"CAPSLOCK"}},
+	{KeyNumLock, []string{"NUMLOCK"}},
+	{KeyScrollLock, []string{"SCROLLLOCK"}},
+	{KeyPrintScreen, []string{"PRINTSCREEN", "PRTSC"}},
+	{KeyPause, []string{"PAUSE"}},
+
+	{KeyNumpad0, []string{"NUMPAD0"}},
+	{KeyNumpad1, []string{"NUMPAD1"}},
+	{KeyNumpad2, []string{"NUMPAD2"}},
+	{KeyNumpad3, []string{"NUMPAD3"}},
+	{KeyNumpad4, []string{"NUMPAD4"}},
+	{KeyNumpad5, []string{"NUMPAD5"}},
+	{KeyNumpad6, []string{"NUMPAD6"}},
+	{KeyNumpad7, []string{"NUMPAD7"}},
+	{KeyNumpad8, []string{"NUMPAD8"}},
+	{KeyNumpad9, []string{"NUMPAD9"}},
+	{KeyNumpadAdd, []string{"NUMPADADD", "NUMPADPLUS"}},
+	{KeyNumpadSubtract, []string{"NUMPADSUBTRACT", "NUMPADMINUS"}},
+	{KeyNumpadMultiply, []string{"NUMPADMULTIPLY"}},
+	{KeyNumpadDivide, []string{"NUMPADDIVIDE"}},
+	{KeyNumpadDecimal, []string{"NUMPADDECIMAL", "NUMPADDOT"}},
+	{KeyNumpadEnter, []string{"NUMPADENTER"}},
+
+	{KeyComma, []string{"COMMA"}},
+	{KeyPeriod, []string{"PERIOD", "DOT"}},
+	{KeyMinus, []string{"MINUS"}},
+	{KeyEqual, []string{"EQUAL", "EQUALS"}},
+	{KeySlash, []string{"SLASH"}},
+	{KeyBackslash, []string{"BACKSLASH"}},
+	{KeySemicolon, []string{"SEMICOLON"}},
+	{KeyQuote, []string{"QUOTE", "APOSTROPHE"}},
+	{KeyBackquote, []string{"BACKQUOTE", "GRAVE"}},
+	{KeyLeftBracket, []string{"LEFTBRACKET"}},
+	{KeyRightBracket, []string{"RIGHTBRACKET"}},
+
+	{KeyVolumeUp, []string{"VOLUMEUP"}},
+	{KeyVolumeDown, []string{"VOLUMEDOWN"}},
+	{KeyVolumeMute, []string{"VOLUMEMUTE", "MUTE"}},
+	{KeyMediaPlayPause, []string{"PLAYPAUSE", "MEDIAPLAYPAUSE"}},
+	{KeyMediaNext, []string{"NEXTTRACK", "MEDIANEXT"}},
+	{KeyMediaPrev, []string{"PREVTRACK", "MEDIAPREV"}},
+	{KeyMediaStop, []string{"MEDIASTOP"}},
+
+	{KeyMouseLeft, []string{"CLICK", "LEFTCLICK", "LCLICK", "MOUSELEFT"}},
+	{KeyMouseRight, []string{"RIGHTCLICK", "RCLICK", "MOUSERIGHT"}},
+	{KeyMouseMiddle, []string{"MIDDLECLICK", "MCLICK", "MOUSEMIDDLE"}},
+
+	{KeyCtrl, []string{"CTRL", "CONTROL"}},
+	{KeyShift, []string{"SHIFT"}},
+	{KeyAlt, []string{"ALT", "OPTION"}},
+	{KeyMeta, []string{"META", "CMD", "COMMAND", "WIN", "SUPER"}},
+}
+
+var (
+	keysByName = map[string]Key{}
+	keyNames   = map[Key]string{}
+)
+
+func init() {
+	for _, entry := range keyTable {
+		keyNames[entry.key] = entry.names[0]
+		for _, name := range entry.names {
+			keysByName[name] = entry.key
+		}
+	}
+}
+
+// LookupKey resolves a key name such as "A", "F5" or "SPACE".
+func LookupKey(name string) (Key, bool) {
+	key, ok := keysByName[strings.ToUpper(strings.TrimSpace(name))]
+	return key, ok
+}
+
+// String returns the canonical key name, as accepted by LookupKey.
+func (k Key) String() string {
+	if name, ok := keyNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("Key(%d)", int(k))
+}
+
+// IsModifier reports whether key can prefix a chord such as CTRL+C.
+func (k Key) IsModifier() bool {
+	switch k {
+	case KeyCtrl, KeyShift, KeyAlt, KeyMeta:
+		return true
+	default:
+		return false
+	}
+}
+
+func checkSupported(task KeyTask, injector Injector) error {
+	if task.UseUnicode {
+		return nil
+	}
+	keys := task.Modifiers
+	if task.Mouse == nil {
+		keys = append([]Key{task.Key}, keys...)
+	}
+	for _, key := range keys {
+		if !injector.Supports(key) {
+			return fmt.Errorf("%s is not supported on this platform", key)
+		}
+	}
+	return nil
+}
+
+type KeyTask struct {
+	Key         Key
+	Modifiers   []Key
+	UnicodeRune rune
+	UseUnicode  bool
+	// Clicks presses Key that many times; DOUBLECLICK sets it to 2. Zero
+	// presses it once.
+	Clicks int
+	// Mouse, when set, replaces Key with a pointer movement or wheel turn.
+	Mouse    *MouseAction
+	Interval time.Duration
+	// Offset delays the task's whole schedule, staggering it against
+	// entries with the same interval.
+	Offset time.Duration
+	Timing Timing
+	Limits Limits
+	// Steps, when set, makes the task a sequence, and Text a text entry;
+	// the key fields are then unused.
+	Steps []TaskStep
+	Text  *TextTask
+	// TypeDelay waits between the characters the task types.
+	TypeDelay time.Duration
+	// Entry is the entry the task was built from. Runner statistics are
+	// keyed by it.
+	Entry *KeyEntry
+}
+
+// ParseKey parses the key column of an entry. A single character is typed as
+// Unicode text so case and layout are preserved; longer input must be a key
+// name, DOUBLECLICK or a MouseAction, optionally prefixed by "+"-separated
+// modifiers (CTRL+SHIFT+T, CTRL+SCROLL 1).
+func ParseKey(input string) (KeyTask, error) {
+	trimmed := strings.TrimSpace(input)
+	if trimmed == "" {
+		return KeyTask{}, fmt.Errorf("empty key")
+	}
+
+	runes := []rune(trimmed)
+	if len(runes) == 1 {
+		return KeyTask{
+			UnicodeRune: runes[0],
+			UseUnicode:  true,
+		}, nil
+	}
+
+	parts := strings.Split(trimmed, "+")
+	// A mouse action's numbers may carry a sign, so it runs to the end.
+	for i, part := range parts {
+		if isMouseAction(part) {
+			parts = append(parts[:i], strings.Join(parts[i:], "+"))
+			break
+		}
+	}
+	var modifiers []Key
+	for _, part := range parts[:len(parts)-1] {
+		modifier, ok := LookupKey(part)
+		if !ok || !modifier.IsModifier() {
+			return KeyTask{}, fmt.Errorf("unsupported modifier %q in %s", strings.TrimSpace(part), input)
+		}
+		modifiers = append(modifiers, modifier)
+	}
+
+	last := parts[len(parts)-1]
+	if isMouseAction(last) {
+		action, err := parseMouseAction(last)
+		if err != nil {
+			return KeyTask{}, err
+		}
+		return KeyTask{Mouse: action, Modifiers: modifiers}, nil
+	}
+	if strings.EqualFold(strings.TrimSpace(last), "DOUBLECLICK") {
+		return KeyTask{Key: KeyMouseLeft, Clicks: 2, Modifiers: modifiers}, nil
+	}
+	key, ok := LookupKey(last)
+	if !ok {
+		return KeyTask{}, fmt.Errorf("unsupported key: %s", input)
+	}
+	return KeyTask{Key: key, Modifiers: modifiers}, nil
+}
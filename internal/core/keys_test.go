@@ -0,0 +1,93 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    KeyTask
+		wantErr bool
+	}{
+		{input: "A", want: KeyTask{UnicodeRune: 'A', UseUnicode: true}},
+		{input: "é", want: KeyTask{UnicodeRune: 'é', UseUnicode: true}},
+		{input: "F5", want: KeyTask{Key: KeyF5}},
+		{input: " space ", want: KeyTask{Key: KeySpace}},
+		{input: "escape", want: KeyTask{Key: KeyEsc}},
+		{input: "NUMPADENTER", want: KeyTask{Key: KeyNumpadEnter}},
+		{input: "pgup", want: KeyTask{Key: KeyPageUp}},
+		{input: "F24", want: KeyTask{Key: KeyF24}},
+		{input: "CTRL+ALT+DEL", want: KeyTask{Key: KeyDelete, Modifiers: []Key{KeyCtrl, KeyAlt}}},
+		{input: "CTRL+C", want: KeyTask{Key: KeyC, Modifiers: []Key{KeyCtrl}}},
+		{input: "CTRL + C", want: KeyTask{Key: KeyC, Modifiers: []Key{KeyCtrl}}},
+		{input: "ctrl+shift+t", want: KeyTask{Key: KeyT, Modifiers: []Key{KeyCtrl, KeyShift}}},
+		{input: "CMD+S", want: KeyTask{Key: KeyS, Modifiers: []Key{KeyMeta}}},
+		{input: "SHIFT+F5", want: KeyTask{Key: KeyF5, Modifiers: []Key{KeyShift}}},
+		{input: "click", want: KeyTask{Key: KeyMouseLeft}},
+		{input: "CTRL+RIGHTCLICK", want: KeyTask{Key: KeyMouseRight, Modifiers: []Key{KeyCtrl}}},
+		{input: "DOUBLECLICK", want: KeyTask{Key: KeyMouseLeft, Clicks: 2}},
+		{input: "MOVE 100 200", want: KeyTask{Mouse: &MouseAction{Kind: MouseMove, X: 100, Y: 200}}},
+		{input: "move 100,200", want: KeyTask{Mouse: &MouseAction{Kind: MouseMove, X: 100, Y: 200}}},
+		{input: "MOVEBY -10 +5", want: KeyTask{Mouse: &MouseAction{Kind: MouseMoveBy, X: -10, Y: 5}}},
+		{input: "CTRL+SCROLL +3", want: KeyTask{Mouse: &MouseAction{Kind: MouseScroll, Y: 3}, Modifiers: []Key{KeyCtrl}}},
+		{input: "SCROLL -2", want: KeyTask{Mouse: &MouseAction{Kind: MouseScroll, Y: -2}}},
+
+		{input: "", wantErr: true},
+		{input: "  ", wantErr: true},
+		{input: "hello", wantErr: true},
+		{input: "A+C", wantErr: true},
+		{input: "CTRL+", wantErr: true},
+		{input: "CTRL+FOO", wantErr: true},
+		{input: "MOVE 100", wantErr: true},
+		{input: "MOVE -1 5", wantErr: true},
+		{input: "MOVEBY 1.5 0", wantErr: true},
+		{input: "SCROLL", wantErr: true},
+		{input: "SCROLL 1 2", wantErr: true},
+		{input: "A+SCROLL 1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseKey(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseKey(%q) = %+v, want an error", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseKey(%q): %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseKey(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestKeyTable(t *testing.T) {
+	seen := map[string]Key{}
+	for _, entry := range keyTable {
+		if got := entry.key.String(); got != entry.names[0] {
+			t.Errorf("%s.String() = %q, want its first name", entry.names[0], got)
+		}
+		for _, name := range entry.names {
+			if other, ok := seen[name]; ok {
+				t.Errorf("%s names both %s and %s", name, other, entry.key)
+			}
+			seen[name] = entry.key
+			if key, ok := LookupKey(strings.ToLower(name)); !ok || key != entry.key {
+				t.Errorf("LookupKey(%q) = %s, %t", name, key, ok)
+			}
+		}
+	}
+	for key := KeyA; key <= KeyMeta; key++ {
+		if _, ok := keyNames[key]; !ok {
+			t.Errorf("key %d has no name", int(key))
+		}
+	}
+	if got := KeyNone.String(); got != "Key(0)" {
+		t.Errorf("KeyNone.String() = %q", got)
+	}
+}
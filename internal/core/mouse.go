@@ -0,0 +1,92 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MouseKind is what a MouseAction does with the pointer. Clicks are not
+// actions but keys (KeyMouseLeft and friends), so they can be held, chorded
+// and used in down and up steps like any other key.
+type MouseKind int
+
+const (
+	// MouseMove moves the pointer to X, Y in screen pixels.
+	MouseMove MouseKind = iota + 1
+	// MouseMoveBy moves the pointer by X, Y pixels.
+	MouseMoveBy
+	// MouseScroll turns the wheel by Y notches, up when positive.
+	MouseScroll
+)
+
+// MouseAction is a pointer movement or wheel turn, parsed from "MOVE 100 200",
+// "MOVEBY -10 0" or "SCROLL -3" in the key column.
+type MouseAction struct {
+	Kind MouseKind
+	X, Y int
+}
+
+// mouseCommands are the words that start a MouseAction and how many numbers
+// follow them.
+var mouseCommands = map[string]struct {
+	kind MouseKind
+	args int
+}{
+	"MOVE":   {MouseMove, 2},
+	"MOVETO": {MouseMove, 2},
+	"MOVEBY": {MouseMoveBy, 2},
+	"SCROLL": {MouseScroll, 1},
+}
+
+// isMouseAction reports whether text starts with a MouseAction command.
+func isMouseAction(text string) bool {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return false
+	}
+	_, ok := mouseCommands[strings.ToUpper(fields[0])]
+	return ok
+}
+
+// parseMouseAction parses text that isMouseAction. The numbers may be
+// separated by spaces or a comma.
+func parseMouseAction(text string) (*MouseAction, error) {
+	fields := strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == ',' })
+	name := strings.ToUpper(fields[0])
+	command := mouseCommands[name]
+	if len(fields)-1 != command.args {
+		if command.args == 1 {
+			return nil, fmt.Errorf("%s needs a number, such as %s -3", name, name)
+		}
+		return nil, fmt.Errorf("%s needs two numbers, such as %s 100 200", name, name)
+	}
+	var values [2]int
+	for i, field := range fields[1:] {
+		value, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not a whole number", name, field)
+		}
+		values[i] = value
+	}
+	if command.kind == MouseMove && (values[0] < 0 || values[1] < 0) {
+		return nil, fmt.Errorf("%s needs a position on screen, not %d %d", name, values[0], values[1])
+	}
+	if command.kind == MouseScroll {
+		return &MouseAction{Kind: MouseScroll, Y: values[0]}, nil
+	}
+	return &MouseAction{Kind: command.kind, X: values[0], Y: values[1]}, nil
+}
+
+func (a *MouseAction) send(injector Injector) error {
+	switch a.Kind {
+	case MouseMove:
+		return injector.MoveMouse(a.X, a.Y, false)
+	case MouseMoveBy:
+		return injector.MoveMouse(a.X, a.Y, true)
+	case MouseScroll:
+		return injector.Scroll(a.Y)
+	default:
+		return fmt.Errorf("unknown mouse action %d", a.Kind)
+	}
+}
@@ -0,0 +1,118 @@
+package core
+
+import "time"
+
+// pauseState is one pause of a run: paused is closed by Pause and resumed by
+// the Resume that follows, after which the runner starts a fresh pauseState.
+type pauseState struct {
+	paused  chan struct{}
+	resumed chan struct{}
+}
+
+func newPauseState() *pauseState {
+	return &pauseState{paused: make(chan struct{}), resumed: make(chan struct{})}
+}
+
+// Pause freezes every task of the current run where it is: the time left
+// until each next press, press counts, the step a sequence is on and the
+// time left of a duration limit are kept, and Resume carries on from there.
+// End times of day keep running. The runner still counts as running.
+func (r *Runner) Pause() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.running || r.paused {
+		return
+	}
+	r.paused = true
+	close(r.pause.paused)
+}
+
+// Resume continues a paused run.
+func (r *Runner) Resume() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.running || !r.paused {
+		return
+	}
+	r.paused = false
+	close(r.pause.resumed)
+	r.pause = newPauseState()
+}
+
+func (r *Runner) IsPaused() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.paused
+}
+
+func (r *Runner) pauseSignal() *pauseState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pause
+}
+
+// waitResumed blocks until pause ends and returns how long it lasted. ok is
+// false if the run was stopped instead.
+func waitResumed(pause *pauseState, stopCh <-chan struct{}) (paused time.Duration, ok bool) {
+	pausedAt := time.Now()
+	select {
+	case <-stopCh:
+		return time.Since(pausedAt), false
+	case <-pause.resumed:
+		return time.Since(pausedAt), true
+	}
+}
+
+// holdWhilePaused returns at once unless the run is paused, in which case it
+// waits for Resume like waitResumed.
+func (r *Runner) holdWhilePaused(stopCh <-chan struct{}, held *heldKeys) (paused time.Duration, ok bool) {
+	pause := r.pauseSignal()
+	select {
+	case <-pause.paused:
+		return r.waitResumedReleased(pause, stopCh, held)
+	default:
+		return 0, true
+	}
+}
+
+// waitResumedReleased is waitResumed with the keys in held let go of for
+// the length of the pause.
+func (r *Runner) waitResumedReleased(pause *pauseState, stopCh <-chan struct{}, held *heldKeys) (paused time.Duration, ok bool) {
+	r.releaseHeld(held, true)
+	paused, ok = waitResumed(pause, stopCh)
+	switch {
+	case ok:
+		r.pressHeld(held)
+	case held != nil:
+		held.keys = nil
+	}
+	return paused, ok
+}
+
+// sleep waits for d, not counting time spent paused, and returns how long
+// it was paused. ok is false if the run was stopped first. held, which may
+// be nil, is released while paused.
+func (r *Runner) sleep(d time.Duration, stopCh <-chan struct{}, held *heldKeys) (paused time.Duration, ok bool) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	end := time.Now().Add(d)
+	for {
+		pause := r.pauseSignal()
+		select {
+		case <-stopCh:
+			return paused, false
+		case <-timer.C:
+			return paused, true
+		case <-pause.paused:
+			timer.Stop()
+			remaining := time.Until(end)
+			pausedFor, ok := r.waitResumedReleased(pause, stopCh, held)
+			paused += pausedFor
+			if !ok {
+				return paused, false
+			}
+			end = time.Now().Add(remaining)
+			timer.Reset(remaining)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package core
+
+import (
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// KeyEvent is a physical key going down or up, as captured while recording a
+// macro.
+type KeyEvent struct {
+	Key  Key
+	Down bool
+	// At is the time since recording started.
+	At time.Duration
+}
+
+// modifierOrder is the order modifiers are written in a chord.
+var modifierOrder = []Key{KeyCtrl, KeyShift, KeyAlt, KeyMeta}
+
+// RecordedSteps turns captured key events into sequence steps, with the time
+// between presses as waits. Letters, and digits without Shift, become type
+// steps so replay gives the same character; other keys and chords with Ctrl,
+// Alt or Meta become key steps such as CTRL+C. A modifier pressed and
+// released on its own becomes a step of its own. Replay taps each key, so
+// releases and auto-repeated presses are dropped.
+func RecordedSteps(events []KeyEvent) []Step {
+	var (
+		steps []Step
+		held  = map[Key]bool{}
+		// lone holds the modifiers pressed since the last other key, with
+		// the time each went down.
+		lone = map[Key]time.Duration{}
+		last time.Duration
+	)
+	add := func(step Step, at time.Duration) {
+		if len(steps) > 0 {
+			if wait := int((at - last).Milliseconds()); wait > 0 {
+				steps = append(steps, Step{Kind: StepWait, WaitMS: wait})
+			}
+		}
+		steps = append(steps, step)
+		last = max(last, at)
+	}
+
+	for _, event := range events {
+		if !event.Down {
+			if at, ok := lone[event.Key]; ok {
+				add(Step{Kind: StepKey, Key: event.Key.String()}, at)
+				delete(lone, event.Key)
+			}
+			delete(held, event.Key)
+			continue
+		}
+		if held[event.Key] {
+			continue
+		}
+		held[event.Key] = true
+		if event.Key.IsModifier() {
+			lone[event.Key] = event.At
+			continue
+		}
+		clear(lone)
+		add(recordedStep(event.Key, held), event.At)
+	}
+	return steps
+}
+
+func recordedStep(key Key, held map[Key]bool) Step {
+	if !held[KeyCtrl] && !held[KeyAlt] && !held[KeyMeta] {
+		if r, ok := typedRune(key, held[KeyShift]); ok {
+			return Step{Kind: StepType, Text: string(r)}
+		}
+	}
+	var names []string
+	for _, modifier := range modifierOrder {
+		if held[modifier] {
+			names = append(names, modifier.String())
+		}
+	}
+	return Step{Kind: StepKey, Key: strings.Join(append(names, key.String()), "+")}
+}
+
+// typedRune is the character key types: letters, upper case with Shift, and
+// digits without it. What Shift does to other keys depends on the keyboard
+// layout, so those stay keys.
+func typedRune(key Key, shift bool) (rune, bool) {
+	switch {
+	case key >= KeyA && key <= KeyZ:
+		r := 'a' + rune(key-KeyA)
+		if shift {
+			r = unicode.ToUpper(r)
+		}
+		return r, true
+	case key >= Key0 && key <= Key9 && !shift:
+		return '0' + rune(key-Key0), true
+	default:
+		return 0, false
+	}
+}
+
+// TrimSteps drops the first and last presses of a recording, such as the keys
+// that started and stopped it, with the waits around them. Waits left at
+// either end are dropped too.
+func TrimSteps(steps []Step, first, last int) []Step {
+	start, end := 0, len(steps)
+	for trimmed := 0; trimmed < first && start < end; start++ {
+		if steps[start].Kind != StepWait {
+			trimmed++
+		}
+	}
+	for trimmed := 0; trimmed < last && end > start; {
+		end--
+		if steps[end].Kind != StepWait {
+			trimmed++
+		}
+	}
+	for start < end && steps[start].Kind == StepWait {
+		start++
+	}
+	for end > start && steps[end-1].Kind == StepWait {
+		end--
+	}
+	return slices.Clone(steps[start:end])
+}
+
+// QuantizeWaits rounds every wait to the nearest multiple of quantumMS.
+// Waits that round to zero are dropped and the type steps they separated are
+// merged, so a quickly typed word becomes one type step. A quantum of zero
+// leaves the steps as they are.
+func QuantizeWaits(steps []Step, quantumMS int) []Step {
+	if quantumMS <= 0 {
+		return slices.Clone(steps)
+	}
+	var quantized []Step
+	for _, step := range steps {
+		if step.Kind == StepWait {
+			step.WaitMS = (step.WaitMS + quantumMS/2) / quantumMS * quantumMS
+			if step.WaitMS == 0 {
+				continue
+			}
+		}
+		if n := len(quantized); n > 0 && step.Kind == StepType && quantized[n-1].Kind == StepType {
+			quantized[n-1].Text += step.Text
+			continue
+		}
+		quantized = append(quantized, step)
+	}
+	return quantized
+}
+
+// StepsDuration is how long the waits and holds of a sequence add up to.
+func StepsDuration(steps []Step) time.Duration {
+	var total time.Duration
+	for _, step := range steps {
+		switch step.Kind {
+		case StepWait:
+			total += time.Duration(step.WaitMS) * time.Millisecond
+		case StepHold:
+			total += time.Duration(step.HoldMS) * time.Millisecond
+		}
+	}
+	return total
+}
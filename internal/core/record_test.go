@@ -0,0 +1,138 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// tap is a key pressed at ms and released 30 ms later.
+func tap(key Key, ms int) []KeyEvent {
+	at := time.Duration(ms) * time.Millisecond
+	return []KeyEvent{{Key: key, Down: true, At: at}, {Key: key, At: at + 30*time.Millisecond}}
+}
+
+func down(key Key, ms int) KeyEvent {
+	return KeyEvent{Key: key, Down: true, At: time.Duration(ms) * time.Millisecond}
+}
+
+func up(key Key, ms int) KeyEvent {
+	return KeyEvent{Key: key, At: time.Duration(ms) * time.Millisecond}
+}
+
+func events(groups ...[]KeyEvent) []KeyEvent {
+	var all []KeyEvent
+	for _, group := range groups {
+		all = append(all, group...)
+	}
+	return all
+}
+
+func TestRecordedSteps(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []KeyEvent
+		want   string
+	}{
+		{
+			name:   "letters, digits and named keys",
+			events: events(tap(KeyH, 100), tap(KeyI, 250), tap(Key2, 400), tap(KeyEnter, 1400)),
+			want:   "type h\nwait 150\ntype i\nwait 150\ntype 2\nwait 1000\nENTER",
+		},
+		{
+			name:   "shift makes capitals",
+			events: []KeyEvent{down(KeyShift, 0), down(KeyA, 50), up(KeyA, 80), up(KeyShift, 90), down(KeyB, 200), up(KeyB, 220)},
+			want:   "type A\nwait 150\ntype b",
+		},
+		{
+			name:   "shift with a digit stays a chord",
+			events: []KeyEvent{down(KeyShift, 0), down(Key1, 50), up(Key1, 80), up(KeyShift, 90)},
+			want:   "SHIFT+1",
+		},
+		{
+			name:   "chords",
+			events: []KeyEvent{down(KeyCtrl, 0), down(KeyShift, 10), down(KeyT, 20), up(KeyT, 40), up(KeyShift, 50), down(KeyC, 300), up(KeyC, 320), up(KeyCtrl, 330)},
+			want:   "CTRL+SHIFT+T\nwait 280\nCTRL+C",
+		},
+		{
+			name:   "lone modifier",
+			events: events(tap(KeyF5, 0), []KeyEvent{down(KeyAlt, 500), up(KeyAlt, 520)}),
+			want:   "F5\nwait 500\nALT",
+		},
+		{
+			name:   "auto-repeat is one press",
+			events: []KeyEvent{down(KeyX, 0), down(KeyX, 500), down(KeyX, 530), up(KeyX, 560), down(KeyX, 700), up(KeyX, 720)},
+			want:   "type x\nwait 700\ntype x",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatSteps(RecordedSteps(tt.events)); got != tt.want {
+				t.Errorf("RecordedSteps = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimSteps(t *testing.T) {
+	steps := []Step{
+		{Kind: StepKey, Key: "F9"},
+		{Kind: StepWait, WaitMS: 400},
+		{Kind: StepType, Text: "a"},
+		{Kind: StepWait, WaitMS: 100},
+		{Kind: StepType, Text: "b"},
+		{Kind: StepWait, WaitMS: 900},
+		{Kind: StepKey, Key: "CTRL+C"},
+	}
+	tests := []struct {
+		first, last int
+		want        []Step
+	}{
+		{0, 0, steps},
+		{1, 1, steps[2:5]},
+		{2, 0, steps[4:]},
+		{3, 3, []Step{}},
+		{5, 0, []Step{}},
+	}
+	for _, tt := range tests {
+		if got := TrimSteps(steps, tt.first, tt.last); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("TrimSteps(%d, %d) = %+v, want %+v", tt.first, tt.last, got, tt.want)
+		}
+	}
+}
+
+func TestQuantizeWaits(t *testing.T) {
+	steps := []Step{
+		{Kind: StepType, Text: "h"},
+		{Kind: StepWait, WaitMS: 40},
+		{Kind: StepType, Text: "i"},
+		{Kind: StepWait, WaitMS: 130},
+		{Kind: StepKey, Key: "ENTER"},
+		{Kind: StepWait, WaitMS: 30},
+		{Kind: StepType, Text: "x"},
+	}
+	tests := []struct {
+		quantum int
+		want    string
+	}{
+		{0, "type h\nwait 40\ntype i\nwait 130\nENTER\nwait 30\ntype x"},
+		{10, "type h\nwait 40\ntype i\nwait 130\nENTER\nwait 30\ntype x"},
+		{100, "type hi\nwait 100\nENTER\ntype x"},
+		{50, "type h\nwait 50\ntype i\nwait 150\nENTER\nwait 50\ntype x"},
+	}
+	for _, tt := range tests {
+		if got := FormatSteps(QuantizeWaits(steps, tt.quantum)); got != tt.want {
+			t.Errorf("QuantizeWaits(%d) = %q, want %q", tt.quantum, got, tt.want)
+		}
+	}
+	if steps[0].Text != "h" || len(steps) != 7 {
+		t.Errorf("QuantizeWaits changed its input: %+v", steps)
+	}
+}
+
+func TestStepsDuration(t *testing.T) {
+	steps := []Step{{Kind: StepKey, Key: "A"}, {Kind: StepWait, WaitMS: 250}, {Kind: StepWait, WaitMS: 1000}}
+	if got := StepsDuration(steps); got != 1250*time.Millisecond {
+		t.Errorf("StepsDuration = %v, want 1.25s", got)
+	}
+}
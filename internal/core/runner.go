@@ -0,0 +1,587 @@
+package core
+
+import (
+	"cmp"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Injector sends synthetic input to the OS. Implementations must be safe for
+// concurrent use since every task runs on its own goroutine.
+type Injector interface {
+	// Supports reports whether the platform can send key.
+	Supports(key Key) bool
+	KeyDown(key Key) error
+	KeyUp(key Key) error
+	TypeRune(r rune) error
+	// MoveMouse moves the pointer to x, y in screen pixels, or by x, y when
+	// relative.
+	MoveMouse(x, y int, relative bool) error
+	// Scroll turns the mouse wheel by notches, up when positive.
+	Scroll(notches int) error
+}
+
+// Press sends one press of task: modifiers go down in order, the key is
+// held for hold (zero taps it) as many times as task.Clicks, then the
+// modifiers are released in reverse order. Modifiers that were pressed are
+// always released, even if a later step fails. Unicode text and mouse
+// actions have no key to hold, so hold is ignored for them.
+func Press(injector Injector, task KeyTask, hold time.Duration) (err error) {
+	if task.UseUnicode {
+		return injector.TypeRune(task.UnicodeRune)
+	}
+
+	for i, modifier := range task.Modifiers {
+		if err := injector.KeyDown(modifier); err != nil {
+			releaseModifiers(injector, task.Modifiers[:i])
+			return err
+		}
+	}
+	defer func() {
+		if releaseErr := releaseModifiers(injector, task.Modifiers); err == nil {
+			err = releaseErr
+		}
+	}()
+
+	if task.Mouse != nil {
+		return task.Mouse.send(injector)
+	}
+	for range max(task.Clicks, 1) {
+		if err := injector.KeyDown(task.Key); err != nil {
+			return err
+		}
+		if hold > 0 {
+			time.Sleep(hold)
+		}
+		if err := injector.KeyUp(task.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func releaseModifiers(injector Injector, modifiers []Key) error {
+	var firstErr error
+	for i := len(modifiers) - 1; i >= 0; i-- {
+		if err := injector.KeyUp(modifiers[i]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+type Runner struct {
+	injector Injector
+	// pressMu keeps presses from different tasks from interleaving, so one
+	// task's modifiers never leak into another task's key. It also guards
+	// held, the number of tasks holding each key down.
+	pressMu sync.Mutex
+	held    map[Key]int
+	mu      sync.Mutex
+	stopCh  chan struct{}
+	// wg is replaced on every Start so a late Stop of one run never waits
+	// on the tasks of the next.
+	wg         *sync.WaitGroup
+	running    bool
+	paused     bool
+	pause      *pauseState
+	seed       uint64
+	seeded     bool
+	onFinished func()
+	// maxFailures stops a task after that many failed presses in a row;
+	// zero keeps it running.
+	maxFailures int
+
+	// The current run: its tasks, indexed by the entry they were built
+	// from when they have one, and what tasks started later need.
+	tasks       map[*taskRun]struct{}
+	entryTasks  map[*KeyEntry]*taskRun
+	start       time.Time
+	runSeed     uint64
+	nextTask    uint64
+	runFailures int
+
+	statsMu sync.Mutex
+	stats   map[*KeyEntry]*TaskStats
+}
+
+// taskRun is the goroutine of one task. stop ends it; done is closed once it
+// has returned. stats, guarded by Runner.statsMu, are the ones it records
+// into: a task that was replaced may still record a press after its
+// successor's stats are in Runner.stats, and must not count it there.
+type taskRun struct {
+	stop    chan struct{}
+	done    chan struct{}
+	stopped bool
+	runStop chan struct{}
+	stats   *TaskStats
+}
+
+// halt asks the task to end. It is called with Runner.mu held.
+func (t *taskRun) halt() {
+	if !t.stopped {
+		t.stopped = true
+		close(t.stop)
+	}
+}
+
+func NewRunner(injector Injector) *Runner {
+	return &Runner{injector: injector, held: map[Key]int{}}
+}
+
+// Seed makes jitter and hold times reproducible: every later Start with the
+// same tasks draws the same sequence for each task. Without a seed each
+// Start draws a fresh one.
+func (r *Runner) Seed(seed uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seed = seed
+	r.seeded = true
+}
+
+// OnFinished sets a function called, on a background goroutine, when no task
+// of a run is left because each reached its stop condition or was removed.
+// It is not called when the run ends through Stop.
+func (r *Runner) OnFinished(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onFinished = fn
+}
+
+// StopAfterFailures makes every later run stop a task once n of its presses
+// in a row have failed, for example when the target refuses synthetic input.
+// The task's stats are marked GaveUp. Zero, the default, keeps failing tasks
+// running.
+func (r *Runner) StopAfterFailures(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxFailures = n
+}
+
+func (r *Runner) Start(tasks []KeyTask) {
+	r.StartIn(tasks, 0, nil)
+}
+
+// StartIn starts the run after delay, giving time to switch to the target
+// window. While waiting the runner counts as running, so Stop cancels it, and
+// countdown (if set) is called on a background goroutine with the whole
+// seconds left, once per second, and with 0 when the delay is over. Task
+// offsets and stop conditions are measured from the end of the delay.
+func (r *Runner) StartIn(tasks []KeyTask, delay time.Duration, countdown func(seconds int)) {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return
+	}
+	r.running = true
+	stopCh := make(chan struct{})
+	r.stopCh = stopCh
+	r.wg = &sync.WaitGroup{}
+	r.pause = newPauseState()
+	r.tasks = make(map[*taskRun]struct{}, len(tasks))
+	r.entryTasks = make(map[*KeyEntry]*taskRun, len(tasks))
+	r.start = time.Now().Add(delay)
+	r.runSeed = r.seed
+	if !r.seeded {
+		r.runSeed = rand.Uint64()
+	}
+	r.nextTask = 0
+	r.runFailures = r.maxFailures
+
+	r.statsMu.Lock()
+	r.stats = make(map[*KeyEntry]*TaskStats, len(tasks))
+	r.statsMu.Unlock()
+
+	if delay > 0 && countdown != nil {
+		go r.runCountdown(r.start, stopCh, countdown)
+	}
+	for _, task := range tasks {
+		r.launch(task, r.start)
+	}
+	if len(tasks) == 0 {
+		onFinished := r.finishLocked()
+		r.mu.Unlock()
+		if onFinished != nil {
+			go onFinished()
+		}
+		return
+	}
+	r.mu.Unlock()
+}
+
+// launch starts task as part of the current run, measuring its offset and
+// stop conditions from start. It is called with r.mu held.
+func (r *Runner) launch(task KeyTask, start time.Time) {
+	run := &taskRun{stop: make(chan struct{}), done: make(chan struct{}), runStop: r.stopCh}
+	r.tasks[run] = struct{}{}
+	if task.Entry != nil {
+		r.entryTasks[task.Entry] = run
+		run.stats = &TaskStats{Interval: task.Interval}
+		r.statsMu.Lock()
+		r.stats[task.Entry] = run.stats
+		r.statsMu.Unlock()
+	}
+
+	// Each task gets its own generator, numbered in launch order, so the
+	// draws do not depend on how the goroutines interleave.
+	rng := rand.New(rand.NewPCG(r.runSeed, r.nextTask))
+	r.nextTask++
+	r.wg.Add(1)
+	go r.runTask(task, rng, run, r.wg, start, r.runFailures)
+}
+
+// taskEnded is called by each task as it returns. The run ends on its own
+// once no task is left, unless Stop is ending it.
+func (r *Runner) taskEnded(run *taskRun) {
+	r.mu.Lock()
+	close(run.done)
+	if r.stopCh != run.runStop {
+		r.mu.Unlock()
+		return
+	}
+	delete(r.tasks, run)
+	for entry, entryRun := range r.entryTasks {
+		if entryRun == run {
+			delete(r.entryTasks, entry)
+		}
+	}
+	if !r.running || len(r.tasks) > 0 {
+		r.mu.Unlock()
+		return
+	}
+	onFinished := r.finishLocked()
+	r.mu.Unlock()
+	if onFinished != nil {
+		onFinished()
+	}
+}
+
+// finishLocked ends the run because no task is left and returns the
+// OnFinished function for the caller to call once r.mu is released.
+func (r *Runner) finishLocked() func() {
+	close(r.stopCh)
+	r.running = false
+	r.paused = false
+	return r.onFinished
+}
+
+// UpdateEntry applies an edit to the current run without disturbing the
+// other tasks: the task built from old, if any, stops and entry starts in its
+// place if it is active, from its first press and with fresh stats. old may
+// be entry itself, or nil for a new entry. Nothing happens while the runner
+// is idle. The error says why entry could not be started; old is stopped
+// regardless. If no task is left the run finishes as if each had reached
+// its stop condition.
+func (r *Runner) UpdateEntry(old, entry *KeyEntry) error {
+	tasks, errs := BuildTasks([]*KeyEntry{entry}, r.injector)
+
+	r.mu.Lock()
+	if !r.running {
+		r.mu.Unlock()
+		return nil
+	}
+	var halted []*taskRun
+	for _, e := range []*KeyEntry{old, entry} {
+		if run, ok := r.entryTasks[e]; ok {
+			delete(r.entryTasks, e)
+			halted = append(halted, run)
+		}
+	}
+	// The replacement starts before the old task stops so the run is never
+	// left without a task in between.
+	if len(tasks) > 0 {
+		start := r.start
+		if now := time.Now(); now.After(start) {
+			start = now
+		}
+		r.launch(tasks[0], start)
+	}
+	for _, run := range halted {
+		run.halt()
+	}
+	r.mu.Unlock()
+
+	for _, run := range halted {
+		<-run.done
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// RemoveEntry stops the task built from entry, if it is running, and leaves
+// the others running.
+func (r *Runner) RemoveEntry(entry *KeyEntry) {
+	r.mu.Lock()
+	run, ok := r.entryTasks[entry]
+	if !ok {
+		r.mu.Unlock()
+		return
+	}
+	delete(r.entryTasks, entry)
+	run.halt()
+	r.mu.Unlock()
+
+	<-run.done
+}
+
+func (r *Runner) runTask(task KeyTask, rng *rand.Rand, run *taskRun, wg *sync.WaitGroup, start time.Time, maxFailures int) {
+	defer wg.Done()
+	defer r.taskEnded(run)
+	defer func() {
+		if p := recover(); p != nil {
+			r.releaseAll()
+			panic(p)
+		}
+	}()
+	stopCh := run.stop
+
+	// Each wait is drawn afresh, so a timer replaces the fixed ticker. The
+	// next wait starts when the previous one fires, not after the press, to
+	// keep the average rate at Interval. The first wait also covers the start
+	// delay and the task's offset.
+	first := time.Until(start) + task.Offset + task.Timing.nextInterval(rng, task.Interval)
+	next := time.Now().Add(first)
+	timer := time.NewTimer(first)
+	defer timer.Stop()
+
+	var (
+		limit   *time.Timer
+		expired <-chan time.Time
+	)
+	if deadline := task.Limits.deadline(start, 0); !deadline.IsZero() {
+		limit = time.NewTimer(time.Until(deadline))
+		defer limit.Stop()
+		expired = limit.C
+	}
+
+	// After a pause both timers are moved later by its length, so the task
+	// picks up with the time it had left.
+	var paused time.Duration
+	shift := func(pausedFor time.Duration) {
+		paused += pausedFor
+		next = next.Add(pausedFor)
+		timer.Reset(time.Until(next))
+		if limit != nil {
+			limit.Reset(time.Until(task.Limits.deadline(start, paused)))
+		}
+		r.recordPause(run, pausedFor)
+	}
+
+	presses := 0
+	for {
+		pause := r.pauseSignal()
+		select {
+		case <-stopCh:
+			return
+		case <-expired:
+			return
+		case <-pause.paused:
+			pausedFor, ok := waitResumed(pause, stopCh)
+			if !ok {
+				return
+			}
+			shift(pausedFor)
+		case <-timer.C:
+			wait := task.Timing.nextInterval(rng, task.Interval)
+			next = time.Now().Add(wait)
+			timer.Reset(wait)
+			at := time.Now()
+			var err error
+			if len(task.Steps) > 0 || task.Text != nil {
+				var (
+					completed bool
+					pausedFor time.Duration
+				)
+				if task.Text != nil {
+					completed, pausedFor, err = r.runText(task, rng, presses+1, stopCh)
+				} else {
+					completed, pausedFor, err = r.runSequence(task, rng, stopCh)
+				}
+				if !completed {
+					r.record(run, at, err, 0)
+					return
+				}
+				if pausedFor > 0 {
+					shift(pausedFor)
+				}
+			} else {
+				err = r.press(task, task.Timing.hold(rng))
+			}
+			if r.record(run, at, err, maxFailures) {
+				return
+			}
+			presses++
+			if task.Limits.MaxPresses > 0 && presses >= task.Limits.MaxPresses {
+				return
+			}
+		}
+	}
+}
+
+// runCountdown stops counting while the run is paused, as the tasks do.
+func (r *Runner) runCountdown(start time.Time, stopCh <-chan struct{}, countdown func(seconds int)) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		left := time.Until(start)
+		if left <= 0 {
+			select {
+			case <-stopCh:
+			default:
+				countdown(0)
+			}
+			return
+		}
+		countdown(int((left + time.Second - 1) / time.Second))
+		pause := r.pauseSignal()
+		select {
+		case <-stopCh:
+			return
+		case <-pause.paused:
+			pausedFor, ok := waitResumed(pause, stopCh)
+			if !ok {
+				return
+			}
+			start = start.Add(pausedFor)
+			ticker.Reset(time.Second)
+		case <-ticker.C:
+		}
+	}
+}
+
+// runSequence runs the task's steps in order on the task's own goroutine, so
+// a sequence never overlaps itself; a run that outlasts the interval delays
+// the next one. Each press is atomic, but other tasks may press during waits.
+// completed is false if the runner was stopped midway; paused is how long the
+// sequence was held by Pause, which resumes it at the step it was on; err is
+// the first step that failed, after which the remaining steps still run.
+// Keys put down by the steps are released while paused and at the end.
+func (r *Runner) runSequence(task KeyTask, rng *rand.Rand, stopCh <-chan struct{}) (completed bool, paused time.Duration, err error) {
+	held := &heldKeys{}
+	defer func() {
+		if releaseErr := r.releaseHeld(held, false); err == nil {
+			err = releaseErr
+		}
+	}()
+	for _, step := range task.Steps {
+		pausedFor, ok := r.holdWhilePaused(stopCh, held)
+		paused += pausedFor
+		if !ok {
+			return false, paused, err
+		}
+		select {
+		case <-stopCh:
+			return false, paused, err
+		default:
+		}
+
+		var stepErr error
+		switch step.Kind {
+		case StepWait:
+			pausedFor, ok := r.sleep(step.Wait, stopCh, held)
+			paused += pausedFor
+			if !ok {
+				return false, paused, err
+			}
+		case StepType:
+			var pausedFor time.Duration
+			pausedFor, ok, stepErr = r.typeText(step.Text, task.TypeDelay, stopCh, held)
+			paused += pausedFor
+			if !ok {
+				return false, paused, cmp.Or(err, stepErr)
+			}
+		case StepDown:
+			stepErr = r.keyDown(held, step.Press)
+		case StepUp:
+			stepErr = r.keyUp(held, step.Press)
+		case StepHold:
+			stepErr = r.keyDown(held, step.Press)
+			pausedFor, ok := r.sleep(step.Wait, stopCh, held)
+			paused += pausedFor
+			if !ok {
+				return false, paused, cmp.Or(err, stepErr)
+			}
+			if upErr := r.keyUp(held, step.Press); stepErr == nil {
+				stepErr = upErr
+			}
+		default:
+			stepErr = r.press(withoutHeld(step.Press, held), task.Timing.hold(rng))
+		}
+		if err == nil {
+			err = stepErr
+		}
+	}
+	return true, paused, err
+}
+
+func (r *Runner) press(task KeyTask, hold time.Duration) error {
+	r.pressMu.Lock()
+	defer r.pressMu.Unlock()
+	return Press(r.injector, task, hold)
+}
+
+// record adds a press to the task's stats and reports whether the task has
+// now failed maxFailures times in a row and must give up.
+func (r *Runner) record(run *taskRun, at time.Time, err error, maxFailures int) (giveUp bool) {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+	stats := run.stats
+	if stats == nil {
+		return false
+	}
+	stats.record(at, err)
+	if maxFailures > 0 && stats.FailStreak >= maxFailures {
+		stats.GaveUp = true
+		return true
+	}
+	return false
+}
+
+func (r *Runner) recordPause(run *taskRun, paused time.Duration) {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+	if run.stats != nil {
+		run.stats.pause(paused)
+	}
+}
+
+// Stats returns a snapshot of each task's statistics for the current run, or
+// the last one once stopped, keyed by the entry the task was built from.
+func (r *Runner) Stats() map[*KeyEntry]TaskStats {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+	snapshot := make(map[*KeyEntry]TaskStats, len(r.stats))
+	for entry, stats := range r.stats {
+		snapshot[entry] = *stats
+	}
+	return snapshot
+}
+
+func (r *Runner) Stop() {
+	r.mu.Lock()
+	if !r.running {
+		r.mu.Unlock()
+		return
+	}
+	close(r.stopCh)
+	r.running = false
+	r.paused = false
+	for run := range r.tasks {
+		run.halt()
+	}
+	wg := r.wg
+	r.mu.Unlock()
+
+	wg.Wait()
+}
+
+func (r *Runner) IsRunning() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running
+}
@@ -0,0 +1,695 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeInjector records what the runner sends as "+KEY", "-KEY", typed
+// runes, "move X Y", "moveby X Y" and "scroll N". Keys in unsupported are
+// reported as such and keys in fail are refused when pressed.
+type fakeInjector struct {
+	mu          sync.Mutex
+	events      []string
+	unsupported map[Key]bool
+	fail        map[Key]bool
+}
+
+func newFakeInjector() *fakeInjector {
+	return &fakeInjector{}
+}
+
+func (f *fakeInjector) Supports(key Key) bool {
+	return !f.unsupported[key]
+}
+
+func (f *fakeInjector) KeyDown(key Key) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail[key] {
+		return errors.New("refused " + key.String())
+	}
+	f.events = append(f.events, "+"+key.String())
+	return nil
+}
+
+func (f *fakeInjector) KeyUp(key Key) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, "-"+key.String())
+	return nil
+}
+
+func (f *fakeInjector) TypeRune(r rune) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, string(r))
+	return nil
+}
+
+func (f *fakeInjector) MoveMouse(x, y int, relative bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	command := "move"
+	if relative {
+		command = "moveby"
+	}
+	f.events = append(f.events, fmt.Sprintf("%s %d %d", command, x, y))
+	return nil
+}
+
+func (f *fakeInjector) Scroll(notches int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, fmt.Sprintf("scroll %d", notches))
+	return nil
+}
+
+func (f *fakeInjector) log() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.events)
+}
+
+func (f *fakeInjector) count(event string) int {
+	n := 0
+	for _, e := range f.log() {
+		if e == event {
+			n++
+		}
+	}
+	return n
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRunnerPressesUntilStopped(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	runner.Start([]KeyTask{
+		{Key: KeyF5, Interval: 5 * time.Millisecond},
+		{UnicodeRune: 'x', UseUnicode: true, Interval: 5 * time.Millisecond},
+	})
+	if !runner.IsRunning() {
+		t.Fatal("IsRunning = false after Start")
+	}
+
+	waitFor(t, "both tasks to press twice", func() bool {
+		return injector.count("+F5") >= 2 && injector.count("x") >= 2
+	})
+	runner.Stop()
+	if runner.IsRunning() {
+		t.Error("IsRunning = true after Stop")
+	}
+
+	presses := len(injector.log())
+	time.Sleep(20 * time.Millisecond)
+	if got := len(injector.log()); got != presses {
+		t.Errorf("%d presses after Stop", got-presses)
+	}
+	runner.Stop()
+}
+
+func TestRunnerStartWhileRunning(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	runner.Start([]KeyTask{{Key: KeyA, Interval: 5 * time.Millisecond}})
+	defer runner.Stop()
+
+	runner.Start([]KeyTask{{Key: KeyB, Interval: time.Millisecond}})
+	waitFor(t, "A to be pressed", func() bool { return injector.count("+A") > 0 })
+	if injector.count("+B") > 0 {
+		t.Error("a second Start ran its tasks alongside the first")
+	}
+}
+
+func TestPress(t *testing.T) {
+	tests := []struct {
+		name    string
+		task    KeyTask
+		fail    Key
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "key",
+			task: KeyTask{Key: KeyF5},
+			want: []string{"+F5", "-F5"},
+		},
+		{
+			name: "rune",
+			task: KeyTask{UnicodeRune: 'é', UseUnicode: true},
+			want: []string{"é"},
+		},
+		{
+			name: "chord",
+			task: KeyTask{Key: KeyT, Modifiers: []Key{KeyCtrl, KeyShift}},
+			want: []string{"+CTRL", "+SHIFT", "+T", "-T", "-SHIFT", "-CTRL"},
+		},
+		{
+			name: "double click",
+			task: KeyTask{Key: KeyMouseLeft, Clicks: 2},
+			want: []string{"+CLICK", "-CLICK", "+CLICK", "-CLICK"},
+		},
+		{
+			name: "mouse action",
+			task: KeyTask{Mouse: &MouseAction{Kind: MouseScroll, Y: 2}, Modifiers: []Key{KeyCtrl}},
+			want: []string{"+CTRL", "scroll 2", "-CTRL"},
+		},
+		{
+			name: "move",
+			task: KeyTask{Mouse: &MouseAction{Kind: MouseMove, X: 10, Y: 20}},
+			want: []string{"move 10 20"},
+		},
+		{
+			name:    "failed key releases the modifiers",
+			task:    KeyTask{Key: KeyT, Modifiers: []Key{KeyCtrl, KeyShift}},
+			fail:    KeyT,
+			want:    []string{"+CTRL", "+SHIFT", "-SHIFT", "-CTRL"},
+			wantErr: true,
+		},
+		{
+			name:    "failed modifier releases the ones before it",
+			task:    KeyTask{Key: KeyT, Modifiers: []Key{KeyCtrl, KeyShift}},
+			fail:    KeyShift,
+			want:    []string{"+CTRL", "-CTRL"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injector := newFakeInjector()
+			injector.fail = map[Key]bool{tt.fail: true}
+			err := Press(injector, tt.task, 0)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Press error = %v, want error %t", err, tt.wantErr)
+			}
+			if got := injector.log(); !slices.Equal(got, tt.want) {
+				t.Errorf("events = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPressHolds(t *testing.T) {
+	injector := newFakeInjector()
+	start := time.Now()
+	if err := Press(injector, KeyTask{Key: KeyW}, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Press returned after %v, want the key held 20ms", elapsed)
+	}
+	if got, want := injector.log(), []string{"+W", "-W"}; !slices.Equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestRunnerRunsSequences(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	steps := []TaskStep{
+		{Kind: StepKey, Press: KeyTask{Key: KeyA, Modifiers: []Key{KeyCtrl}}},
+		{Kind: StepWait, Wait: time.Millisecond},
+		{Kind: StepType, Text: "hi"},
+	}
+	runner.Start([]KeyTask{{Steps: steps, Interval: 5 * time.Millisecond}})
+	waitFor(t, "the sequence to run", func() bool { return injector.count("i") > 0 })
+	runner.Stop()
+
+	want := []string{"+CTRL", "+A", "-A", "-CTRL", "h", "i"}
+	if got := injector.log(); !slices.Equal(got[:len(want)], want) {
+		t.Errorf("events = %q, want them to start with %q", got, want)
+	}
+}
+
+func TestRunnerHoldsKeys(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	steps := []TaskStep{
+		{Kind: StepDown, Press: KeyTask{Key: KeyShift}},
+		{Kind: StepKey, Press: KeyTask{Key: KeyA, Modifiers: []Key{KeyShift}}},
+		{Kind: StepHold, Press: KeyTask{Key: KeyW}, Wait: 20 * time.Millisecond},
+		{Kind: StepUp, Press: KeyTask{Key: KeyShift}},
+		{Kind: StepDown, Press: KeyTask{Key: KeyF5, Modifiers: []Key{KeyCtrl}}},
+	}
+	runner.Start([]KeyTask{{Steps: steps, Interval: 5 * time.Millisecond}})
+	waitFor(t, "the sequence to end", func() bool { return injector.count("-CTRL") > 0 })
+	runner.Stop()
+
+	// SHIFT+A leaves the held SHIFT down, and the keys still down at the end
+	// of the sequence are released.
+	want := []string{"+SHIFT", "+A", "-A", "+W", "-W", "-SHIFT", "+CTRL", "+F5", "-F5", "-CTRL"}
+	if got := injector.log(); !slices.Equal(got[:len(want)], want) {
+		t.Errorf("events = %q, want them to start with %q", got, want)
+	}
+}
+
+func TestRunnerReleasesHeldKeys(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	steps := []TaskStep{
+		{Kind: StepDown, Press: KeyTask{Key: KeyShift}},
+		{Kind: StepHold, Press: KeyTask{Key: KeyW}, Wait: 10 * time.Second},
+	}
+	runner.Start([]KeyTask{{Steps: steps, Interval: 5 * time.Millisecond}})
+	waitFor(t, "W to go down", func() bool { return injector.count("+W") > 0 })
+
+	runner.Pause()
+	waitFor(t, "the keys to be released on Pause", func() bool { return injector.count("-SHIFT") > 0 })
+	runner.Resume()
+	waitFor(t, "the keys to go back down on Resume", func() bool { return injector.count("+SHIFT") == 2 })
+	runner.Stop()
+
+	want := []string{"+SHIFT", "+W", "-W", "-SHIFT", "+SHIFT", "+W", "-W", "-SHIFT"}
+	if got := injector.log(); !slices.Equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+	if len(runner.held) > 0 {
+		t.Errorf("runner still counts %v as held", runner.held)
+	}
+}
+
+func TestRunnerStopsDuringWait(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	steps := []TaskStep{
+		{Kind: StepKey, Press: KeyTask{Key: KeyF5}},
+		{Kind: StepWait, Wait: 10 * time.Second},
+		{Kind: StepKey, Press: KeyTask{Key: KeyF6}},
+	}
+	runner.Start([]KeyTask{{Steps: steps, Interval: time.Millisecond}})
+	waitFor(t, "F5", func() bool { return injector.count("-F5") > 0 })
+
+	start := time.Now()
+	runner.Stop()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Stop took %v during a wait", elapsed)
+	}
+	if injector.count("+F6") > 0 {
+		t.Error("the step after the wait ran")
+	}
+}
+
+func TestRunnerMaxPressesFinishes(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	finished := make(chan struct{})
+	runner.OnFinished(func() { close(finished) })
+
+	task := KeyTask{
+		Key: KeyC, Modifiers: []Key{KeyCtrl},
+		Interval: 5 * time.Millisecond,
+		Limits:   Limits{MaxPresses: 3},
+	}
+	runner.Start([]KeyTask{task})
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		runner.Stop()
+		t.Fatal("the run did not finish on its own")
+	}
+
+	if runner.IsRunning() {
+		t.Error("runner still running after every task finished")
+	}
+	want := []string{"+CTRL", "+C", "-C", "-CTRL", "+CTRL", "+C", "-C", "-CTRL", "+CTRL", "+C", "-C", "-CTRL"}
+	if got := injector.log(); !slices.Equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestRunnerMaxDurationFinishes(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	finished := make(chan struct{})
+	runner.OnFinished(func() { close(finished) })
+
+	runner.Start([]KeyTask{
+		{Key: KeyF5, Interval: time.Millisecond, Limits: Limits{MaxDuration: 20 * time.Millisecond}},
+		{Key: KeyF6, Interval: time.Millisecond, Limits: Limits{MaxPresses: 1}},
+	})
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		runner.Stop()
+		t.Fatal("the run did not finish on its own")
+	}
+	if injector.count("+F5") == 0 || injector.count("+F6") != 1 {
+		t.Errorf("events = %q, want F5 pressed and F6 pressed once", injector.log())
+	}
+}
+
+func TestRunnerStopSkipsOnFinished(t *testing.T) {
+	runner := NewRunner(newFakeInjector())
+	called := make(chan struct{}, 1)
+	runner.OnFinished(func() { called <- struct{}{} })
+
+	runner.Start([]KeyTask{{Key: KeyF5, Interval: time.Millisecond}})
+	runner.Stop()
+	select {
+	case <-called:
+		t.Error("OnFinished was called after Stop")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestRunnerStartInCountsDown(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	var mu sync.Mutex
+	var seconds []int
+	runner.StartIn([]KeyTask{{Key: KeyF5, Interval: time.Millisecond}}, 1500*time.Millisecond, func(left int) {
+		mu.Lock()
+		defer mu.Unlock()
+		seconds = append(seconds, left)
+	})
+	defer runner.Stop()
+
+	if !runner.IsRunning() {
+		t.Error("runner not running during the delay")
+	}
+	time.Sleep(500 * time.Millisecond)
+	if injector.count("+F5") > 0 {
+		t.Fatal("F5 was pressed during the delay")
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for injector.count("+F5") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("F5 was never pressed after the delay")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	waitFor(t, "the countdown to end", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(seconds) > 0 && seconds[len(seconds)-1] == 0
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []int{2, 1, 0}; !slices.Equal(seconds, want) {
+		t.Errorf("countdown = %v, want %v", seconds, want)
+	}
+}
+
+func TestRunnerStopCancelsDelay(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	called := make(chan int, 10)
+	runner.StartIn([]KeyTask{{Key: KeyF5, Interval: time.Millisecond}}, 10*time.Second, func(left int) { called <- left })
+	if left := <-called; left != 10 {
+		t.Errorf("first countdown = %d, want 10", left)
+	}
+
+	start := time.Now()
+	runner.Stop()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Stop took %v during the delay", elapsed)
+	}
+	if injector.count("+F5") > 0 {
+		t.Error("F5 was pressed after Stop")
+	}
+}
+
+func TestRunnerOffsetDelaysFirstPress(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	runner.Start([]KeyTask{
+		{Key: KeyF5, Interval: 5 * time.Millisecond},
+		{Key: KeyF6, Interval: 5 * time.Millisecond, Offset: 10 * time.Second},
+	})
+	waitFor(t, "F5 to be pressed twice", func() bool { return injector.count("+F5") >= 2 })
+	runner.Stop()
+	if injector.count("+F6") > 0 {
+		t.Error("F6 was pressed before its offset")
+	}
+}
+
+func TestRunnerStats(t *testing.T) {
+	injector := newFakeInjector()
+	injector.fail = map[Key]bool{KeyF6: true}
+	runner := NewRunner(injector)
+	finished := make(chan struct{})
+	runner.OnFinished(func() { close(finished) })
+
+	good := &KeyEntry{Key: "F5", IntervalMS: 5, Enabled: true, MaxPresses: 3}
+	bad := &KeyEntry{Key: "F6", IntervalMS: 5, Enabled: true, MaxPresses: 2}
+	tasks, errs := BuildTasks([]*KeyEntry{good, bad}, injector)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	runner.Start(tasks)
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		runner.Stop()
+		t.Fatal("the run did not finish on its own")
+	}
+
+	stats := runner.Stats()
+	if got := stats[good]; got.Presses != 3 || got.Failures != 0 || got.Interval != 5*time.Millisecond {
+		t.Errorf("F5 stats = %+v, want 3 presses at 5ms and no failures", got)
+	}
+	if got := stats[bad]; got.Presses != 2 || got.Failures != 2 || got.LastError == nil {
+		t.Errorf("F6 stats = %+v, want 2 failed presses", got)
+	}
+}
+
+func TestRunnerStopAfterFailures(t *testing.T) {
+	injector := newFakeInjector()
+	injector.fail = map[Key]bool{KeyF6: true}
+	runner := NewRunner(injector)
+	runner.StopAfterFailures(3)
+	defer runner.Stop()
+
+	good := &KeyEntry{Key: "F5", IntervalMS: 5, Enabled: true}
+	bad := &KeyEntry{Key: "F6", IntervalMS: 5, Enabled: true}
+	tasks, errs := BuildTasks([]*KeyEntry{good, bad}, injector)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	runner.Start(tasks)
+	time.Sleep(100 * time.Millisecond)
+
+	if !runner.IsRunning() {
+		t.Fatal("the run ended although F5 still works")
+	}
+	stats := runner.Stats()
+	if got := stats[bad]; !got.GaveUp || got.Presses != 3 || got.FailStreak != 3 {
+		t.Errorf("F6 stats = %+v, want it to give up after 3 failed presses", got)
+	}
+	if got := stats[good]; got.GaveUp || got.Presses <= 3 {
+		t.Errorf("F5 stats = %+v, want it to keep pressing", got)
+	}
+}
+
+func TestRunnerPauseResume(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	finished := make(chan struct{})
+	runner.OnFinished(func() { close(finished) })
+
+	runner.Start([]KeyTask{{Key: KeyF5, Interval: 20 * time.Millisecond, Limits: Limits{MaxPresses: 5}}})
+	waitFor(t, "two presses", func() bool { return injector.count("-F5") >= 2 })
+	runner.Pause()
+	if !runner.IsPaused() || !runner.IsRunning() {
+		t.Fatalf("after Pause: paused %t, running %t; want both", runner.IsPaused(), runner.IsRunning())
+	}
+	pressed := injector.count("-F5")
+	time.Sleep(100 * time.Millisecond)
+	if got := injector.count("-F5"); got != pressed {
+		t.Fatalf("F5 pressed %d times while paused", got-pressed)
+	}
+
+	runner.Resume()
+	if runner.IsPaused() {
+		t.Error("still paused after Resume")
+	}
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		runner.Stop()
+		t.Fatal("the run did not finish after resuming")
+	}
+	if got := injector.count("-F5"); got != 5 {
+		t.Errorf("F5 pressed %d times, want the 5 of its limit", got)
+	}
+}
+
+func TestRunnerPauseKeepsSequenceStep(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	steps := []TaskStep{
+		{Kind: StepKey, Press: KeyTask{Key: KeyF5}},
+		{Kind: StepWait, Wait: 60 * time.Millisecond},
+		{Kind: StepKey, Press: KeyTask{Key: KeyF6}},
+	}
+	runner.Start([]KeyTask{{Steps: steps, Interval: 300 * time.Millisecond}})
+	defer runner.Stop()
+	waitFor(t, "F5", func() bool { return injector.count("-F5") > 0 })
+
+	runner.Pause()
+	time.Sleep(150 * time.Millisecond)
+	if injector.count("+F6") > 0 {
+		t.Fatal("the step after the wait ran while paused")
+	}
+	runner.Resume()
+	waitFor(t, "F6 after resuming", func() bool { return injector.count("-F6") > 0 })
+	if got := injector.count("+F5"); got != 1 {
+		t.Errorf("F5 pressed %d times, want the sequence to resume rather than restart", got)
+	}
+}
+
+func TestRunnerStopWhilePaused(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	runner.Start([]KeyTask{{Key: KeyF5, Interval: 5 * time.Millisecond}})
+	runner.Pause()
+	runner.Stop()
+	if runner.IsRunning() || runner.IsPaused() {
+		t.Errorf("after Stop: running %t, paused %t; want neither", runner.IsRunning(), runner.IsPaused())
+	}
+
+	runner.Resume()
+	runner.Start([]KeyTask{{Key: KeyF6, Interval: 5 * time.Millisecond}})
+	defer runner.Stop()
+	waitFor(t, "F6 in the next run", func() bool { return injector.count("-F6") > 0 })
+}
+
+func TestRunnerUpdateEntryLive(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	f5 := &KeyEntry{Key: "F5", IntervalMS: 5, Enabled: true}
+	f6 := &KeyEntry{Key: "F6", IntervalMS: 5, Enabled: true}
+	tasks, errs := BuildTasks([]*KeyEntry{f5, f6}, injector)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	runner.Start(tasks)
+	defer runner.Stop()
+	waitFor(t, "F6", func() bool { return injector.count("-F6") > 0 })
+
+	runner.RemoveEntry(f6)
+	pressed := injector.count("-F6")
+	before := injector.count("-F5")
+	time.Sleep(50 * time.Millisecond)
+	if got := injector.count("-F6"); got != pressed {
+		t.Errorf("F6 pressed %d times after being removed", got-pressed)
+	}
+	if injector.count("-F5") == before {
+		t.Error("F5 stopped along with F6")
+	}
+
+	f5.IntervalMS = 7
+	if err := runner.UpdateEntry(f5, f5); err != nil {
+		t.Fatal(err)
+	}
+	if got := runner.Stats()[f5].Interval; got != 7*time.Millisecond {
+		t.Errorf("F5 interval after the edit = %v, want 7ms", got)
+	}
+
+	f7 := &KeyEntry{Key: "F7", IntervalMS: 5, Enabled: true}
+	if err := runner.UpdateEntry(nil, f7); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the added F7", func() bool { return injector.count("-F7") > 0 })
+
+	if err := runner.UpdateEntry(nil, &KeyEntry{Key: "hello", IntervalMS: 5, Enabled: true}); err == nil {
+		t.Error("UpdateEntry accepted an unknown key")
+	}
+	if !runner.IsRunning() {
+		t.Error("the run ended after live edits")
+	}
+}
+
+func TestRunnerUpdateEntryReplacesEntry(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	old := &KeyEntry{Key: "F5", IntervalMS: 5, Enabled: true}
+	tasks, _ := BuildTasks([]*KeyEntry{old}, injector)
+	runner.Start(tasks)
+	defer runner.Stop()
+
+	edited := *old
+	edited.Key = "F6"
+	if err := runner.UpdateEntry(old, &edited); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "F6", func() bool { return injector.count("-F6") > 0 })
+	pressed := injector.count("-F5")
+	time.Sleep(30 * time.Millisecond)
+	if got := injector.count("-F5"); got != pressed {
+		t.Errorf("the replaced F5 pressed %d more times", got-pressed)
+	}
+}
+
+func TestRunnerUpdateEntryStartsFreshStats(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	entry := &KeyEntry{IntervalMS: 1, Enabled: true, Text: "abcdef", TypeDelayMS: 1000}
+	tasks, _ := BuildTasks([]*KeyEntry{entry}, injector)
+	runner.Start(tasks)
+	defer runner.Stop()
+	waitFor(t, "the first character", func() bool { return injector.count("a") > 0 })
+
+	// The old task is stopped while typing and records that run as it
+	// returns, after the edited entry's stats are in place.
+	entry.IntervalMS = int(time.Hour / time.Millisecond)
+	if err := runner.UpdateEntry(entry, entry); err != nil {
+		t.Fatal(err)
+	}
+	if got := runner.Stats()[entry].Presses; got != 0 {
+		t.Errorf("edited entry starts with %d presses, want 0", got)
+	}
+}
+
+func TestRunnerDisablingLastEntryFinishes(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	finished := make(chan struct{})
+	runner.OnFinished(func() { close(finished) })
+	entry := &KeyEntry{Key: "F5", IntervalMS: 5, Enabled: true}
+	tasks, _ := BuildTasks([]*KeyEntry{entry}, injector)
+	runner.Start(tasks)
+
+	entry.Enabled = false
+	if err := runner.UpdateEntry(entry, entry); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		runner.Stop()
+		t.Fatal("the run did not finish once its only entry was disabled")
+	}
+	if runner.IsRunning() {
+		t.Error("runner still running")
+	}
+}
+
+func TestRunnerUpdateEntryWhileIdle(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	if err := runner.UpdateEntry(nil, &KeyEntry{Key: "F5", IntervalMS: 5, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	runner.RemoveEntry(&KeyEntry{})
+	time.Sleep(20 * time.Millisecond)
+	if runner.IsRunning() || len(injector.log()) > 0 {
+		t.Error("UpdateEntry started an idle runner")
+	}
+}
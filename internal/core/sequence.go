@@ -0,0 +1,213 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type StepKind int
+
+const (
+	// StepKey presses Key, which uses the same syntax as KeyEntry.Key.
+	StepKey StepKind = iota
+	// StepWait pauses for WaitMS.
+	StepWait
+	// StepType types Text as Unicode.
+	StepType
+	// StepDown presses Key (modifiers first) and leaves it held.
+	StepDown
+	// StepUp releases Key (modifiers last).
+	StepUp
+	// StepHold holds Key down for HoldMS.
+	StepHold
+)
+
+// Step is one action of a sequence entry.
+type Step struct {
+	Kind   StepKind
+	Key    string
+	WaitMS int
+	Text   string
+	HoldMS int
+}
+
+// TaskStep is a parsed Step ready for the runner.
+type TaskStep struct {
+	Kind  StepKind
+	Press KeyTask
+	// Wait is how long a wait step pauses or a hold step holds.
+	Wait time.Duration
+	Text string
+}
+
+// ParseSteps reads the sequence editor's text, one step per line:
+//
+//	CTRL+A
+//	wait 50
+//	type hello
+//	down SHIFT
+//	hold W 2s
+//	up SHIFT
+//
+// wait and hold take milliseconds or a Go duration such as 1.5s. Keys put
+// down stay held until their up step or the end of the run. Blank lines and
+// lines starting with # are ignored.
+func ParseSteps(text string) ([]Step, error) {
+	var steps []Step
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		step, err := parseStep(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("a sequence needs at least one step")
+	}
+	return steps, nil
+}
+
+func parseStep(line string) (Step, error) {
+	command, rest, _ := strings.Cut(line, " ")
+	switch strings.ToLower(command) {
+	case "wait":
+		rest = strings.TrimSpace(rest)
+		if rest == "" {
+			return Step{}, fmt.Errorf("wait needs a duration")
+		}
+		ms, err := ParseDurationMS(rest)
+		if err != nil {
+			return Step{}, err
+		}
+		return Step{Kind: StepWait, WaitMS: ms}, nil
+	case "type":
+		text := unquote(strings.TrimSpace(rest))
+		if text == "" {
+			return Step{}, fmt.Errorf("type needs some text")
+		}
+		return Step{Kind: StepType, Text: text}, nil
+	case "down", "up":
+		key := strings.TrimSpace(rest)
+		if key == "" {
+			return Step{}, fmt.Errorf("%s needs a key", strings.ToLower(command))
+		}
+		if _, err := parseHeldKey(key); err != nil {
+			return Step{}, err
+		}
+		kind := StepDown
+		if strings.EqualFold(command, "up") {
+			kind = StepUp
+		}
+		return Step{Kind: kind, Key: key}, nil
+	case "hold":
+		fields := strings.Fields(rest)
+		if len(fields) != 2 {
+			return Step{}, fmt.Errorf("hold needs a key and a duration, such as hold W 2s")
+		}
+		if _, err := parseHeldKey(fields[0]); err != nil {
+			return Step{}, err
+		}
+		ms, err := ParseDurationMS(fields[1])
+		if err != nil {
+			return Step{}, err
+		}
+		return Step{Kind: StepHold, Key: fields[0], HoldMS: ms}, nil
+	default:
+		if _, err := ParseKey(line); err != nil {
+			return Step{}, err
+		}
+		return Step{Kind: StepKey, Key: line}, nil
+	}
+}
+
+// parseHeldKey parses the key of a down, up or hold step. Unlike ParseKey a
+// single letter or digit is its key rather than a typed character, which
+// could not be held; other single characters, double clicks and mouse
+// actions are refused.
+func parseHeldKey(key string) (KeyTask, error) {
+	task, err := ParseKey(key)
+	if err != nil {
+		return task, err
+	}
+	if task.Mouse != nil || task.Clicks > 1 {
+		return KeyTask{}, fmt.Errorf("%s cannot be held down", strings.TrimSpace(key))
+	}
+	if !task.UseUnicode {
+		return task, nil
+	}
+	if k, ok := LookupKey(key); ok {
+		return KeyTask{Key: k}, nil
+	}
+	return KeyTask{}, fmt.Errorf("%s cannot be held down", strings.TrimSpace(key))
+}
+
+// unquote strips one pair of matching quotes, so type 'a b ' keeps its
+// trailing space.
+func unquote(text string) string {
+	if len(text) >= 2 && (text[0] == '\'' || text[0] == '"') && text[len(text)-1] == text[0] {
+		return text[1 : len(text)-1]
+	}
+	return text
+}
+
+// FormatSteps is the inverse of ParseSteps.
+func FormatSteps(steps []Step) string {
+	lines := make([]string, 0, len(steps))
+	for _, step := range steps {
+		switch step.Kind {
+		case StepWait:
+			lines = append(lines, fmt.Sprintf("wait %d", step.WaitMS))
+		case StepType:
+			text := step.Text
+			if strings.TrimSpace(text) != text {
+				text = "'" + text + "'"
+			}
+			lines = append(lines, "type "+text)
+		case StepDown:
+			lines = append(lines, "down "+step.Key)
+		case StepUp:
+			lines = append(lines, "up "+step.Key)
+		case StepHold:
+			lines = append(lines, fmt.Sprintf("hold %s %d", step.Key, step.HoldMS))
+		default:
+			lines = append(lines, step.Key)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func buildSteps(steps []Step, injector Injector) ([]TaskStep, error) {
+	built := make([]TaskStep, 0, len(steps))
+	for i, step := range steps {
+		taskStep := TaskStep{Kind: step.Kind}
+		switch step.Kind {
+		case StepWait:
+			taskStep.Wait = time.Duration(step.WaitMS) * time.Millisecond
+		case StepType:
+			taskStep.Text = UnescapeText(step.Text)
+		default:
+			if step.Kind == StepHold {
+				taskStep.Wait = time.Duration(step.HoldMS) * time.Millisecond
+			}
+			parse := ParseKey
+			if step.Kind != StepKey {
+				parse = parseHeldKey
+			}
+			press, err := parse(step.Key)
+			if err == nil && injector != nil {
+				err = checkSupported(press, injector)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("step %d: %w", i+1, err)
+			}
+			taskStep.Press = press
+		}
+		built = append(built, taskStep)
+	}
+	return built, nil
+}
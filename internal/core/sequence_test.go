@@ -0,0 +1,119 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStepsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		// formatted is what FormatSteps writes back; empty means text.
+		formatted string
+		want      []Step
+	}{
+		{
+			name: "keys and waits",
+			text: "CTRL+A\nwait 50\nTAB",
+			want: []Step{{Kind: StepKey, Key: "CTRL+A"}, {Kind: StepWait, WaitMS: 50}, {Kind: StepKey, Key: "TAB"}},
+		},
+		{
+			name:      "wait as a duration",
+			text:      "wait 1.5s",
+			formatted: "wait 1500",
+			want:      []Step{{Kind: StepWait, WaitMS: 1500}},
+		},
+		{
+			name: "type",
+			text: "type hello world",
+			want: []Step{{Kind: StepType, Text: "hello world"}},
+		},
+		{
+			name: "type with spaces kept",
+			text: "type ' a b '",
+			want: []Step{{Kind: StepType, Text: " a b "}},
+		},
+		{
+			name:      "down, hold and up",
+			text:      "down SHIFT\nHOLD W 2s\nup shift",
+			formatted: "down SHIFT\nhold W 2000\nup shift",
+			want: []Step{
+				{Kind: StepDown, Key: "SHIFT"},
+				{Kind: StepHold, Key: "W", HoldMS: 2000},
+				{Kind: StepUp, Key: "shift"},
+			},
+		},
+		{
+			name: "mouse",
+			text: "MOVE 640 360\nCLICK\ndown CLICK\nMOVEBY 200 0\nup CLICK\nSCROLL -3",
+			want: []Step{
+				{Kind: StepKey, Key: "MOVE 640 360"},
+				{Kind: StepKey, Key: "CLICK"},
+				{Kind: StepDown, Key: "CLICK"},
+				{Kind: StepKey, Key: "MOVEBY 200 0"},
+				{Kind: StepUp, Key: "CLICK"},
+				{Kind: StepKey, Key: "SCROLL -3"},
+			},
+		},
+		{
+			name:      "comments and blank lines",
+			text:      "# start\n\nF5\n  WAIT 10  ",
+			formatted: "F5\nwait 10",
+			want:      []Step{{Kind: StepKey, Key: "F5"}, {Kind: StepWait, WaitMS: 10}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := ParseSteps(tt.text)
+			if err != nil {
+				t.Fatalf("ParseSteps: %v", err)
+			}
+			if !reflect.DeepEqual(steps, tt.want) {
+				t.Fatalf("ParseSteps = %+v, want %+v", steps, tt.want)
+			}
+			formatted := FormatSteps(steps)
+			want := tt.formatted
+			if want == "" {
+				want = tt.text
+			}
+			if formatted != want {
+				t.Fatalf("FormatSteps = %q, want %q", formatted, want)
+			}
+			again, err := ParseSteps(formatted)
+			if err != nil {
+				t.Fatalf("ParseSteps(FormatSteps): %v", err)
+			}
+			if !reflect.DeepEqual(again, steps) {
+				t.Errorf("round trip = %+v, want %+v", again, steps)
+			}
+		})
+	}
+}
+
+func TestParseStepsErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"# only a comment",
+		"wait",
+		"wait soon",
+		"wait -5",
+		"type",
+		"NOTAKEY",
+		"down",
+		"up NOTAKEY",
+		"down é",
+		"hold W",
+		"hold W soon",
+		"hold CTRL+W 1s extra",
+		"down DOUBLECLICK",
+		"down SCROLL 1",
+	} {
+		if steps, err := ParseSteps(text); err == nil {
+			t.Errorf("ParseSteps(%q) = %+v, want an error", text, steps)
+		}
+	}
+	if _, err := ParseSteps("F5\n\nNOTAKEY"); err == nil || err.Error() != "line 3: unsupported key: NOTAKEY" {
+		t.Errorf("error = %v, want it to name line 3", err)
+	}
+}
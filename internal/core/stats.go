@@ -0,0 +1,74 @@
+package core
+
+import "time"
+
+// TaskStats describes how a task has actually been firing in the current or
+// most recent run.
+type TaskStats struct {
+	// Presses counts attempts, including failed ones; a sequence run counts
+	// once.
+	Presses   int
+	Failures  int
+	LastPress time.Time
+	LastError error
+	// FailStreak counts the failures since the last successful press.
+	FailStreak int
+	// GaveUp is set when the runner stopped the task after too many
+	// failures in a row, see Runner.StopAfterFailures.
+	GaveUp bool
+	// Interval is the configured interval, for comparing against the
+	// measured one.
+	Interval time.Duration
+
+	first time.Time
+	// paused is the time spent paused between the first and last press,
+	// which MeanInterval leaves out; pausing is the pause since the last
+	// press, counted once another press follows.
+	paused  time.Duration
+	pausing time.Duration
+}
+
+// MeanInterval is the measured average time between presses, or zero before
+// the second press.
+func (s TaskStats) MeanInterval() time.Duration {
+	if s.Presses < 2 {
+		return 0
+	}
+	return (s.LastPress.Sub(s.first) - s.paused) / time.Duration(s.Presses-1)
+}
+
+// Drift is how much later than configured the presses land on average.
+// Jitter averages out, so a steady positive drift means the presses, the
+// injector or the machine cannot keep up.
+func (s TaskStats) Drift() time.Duration {
+	mean := s.MeanInterval()
+	if mean == 0 {
+		return 0
+	}
+	return mean - s.Interval
+}
+
+func (s *TaskStats) record(at time.Time, err error) {
+	if s.Presses == 0 {
+		s.first = at
+	}
+	s.paused += s.pausing
+	s.pausing = 0
+	s.Presses++
+	s.LastPress = at
+	if err != nil {
+		s.Failures++
+		s.FailStreak++
+		s.LastError = err
+	} else {
+		s.FailStreak = 0
+	}
+}
+
+// pause notes a pause of the run. Pauses before the first press do not
+// affect the measured interval.
+func (s *TaskStats) pause(d time.Duration) {
+	if s.Presses > 0 {
+		s.pausing += d
+	}
+}
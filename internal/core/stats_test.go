@@ -0,0 +1,50 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTaskStats(t *testing.T) {
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	stats := TaskStats{Interval: time.Second}
+	if stats.MeanInterval() != 0 || stats.Drift() != 0 {
+		t.Errorf("empty stats: mean %v, drift %v; want 0", stats.MeanInterval(), stats.Drift())
+	}
+
+	stats.record(start, nil)
+	if stats.MeanInterval() != 0 {
+		t.Errorf("MeanInterval after one press = %v, want 0", stats.MeanInterval())
+	}
+	failure := errors.New("refused")
+	stats.record(start.Add(1010*time.Millisecond), failure)
+	stats.record(start.Add(2020*time.Millisecond), nil)
+
+	if stats.Presses != 3 || stats.Failures != 1 || stats.LastError != failure {
+		t.Errorf("stats = %+v, want 3 presses and 1 failure", stats)
+	}
+	if stats.FailStreak != 0 {
+		t.Errorf("FailStreak = %d after a successful press, want 0", stats.FailStreak)
+	}
+	if !stats.LastPress.Equal(start.Add(2020 * time.Millisecond)) {
+		t.Errorf("LastPress = %v", stats.LastPress)
+	}
+	if got := stats.MeanInterval(); got != 1010*time.Millisecond {
+		t.Errorf("MeanInterval = %v, want 1.01s", got)
+	}
+	if got := stats.Drift(); got != 10*time.Millisecond {
+		t.Errorf("Drift = %v, want 10ms", got)
+	}
+
+	// A pause between two presses is left out of the measured interval,
+	// but only once the next press lands.
+	stats.pause(time.Minute)
+	if got := stats.MeanInterval(); got != 1010*time.Millisecond {
+		t.Errorf("MeanInterval while paused = %v, want 1.01s", got)
+	}
+	stats.record(start.Add(time.Minute+3030*time.Millisecond), nil)
+	if got := stats.MeanInterval(); got != 1010*time.Millisecond {
+		t.Errorf("MeanInterval after a pause = %v, want 1.01s", got)
+	}
+}
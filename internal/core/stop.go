@@ -0,0 +1,121 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const stopAtLayout = "15:04"
+
+// Limits end a task on its own. Zero values mean no limit; the first limit
+// reached wins.
+type Limits struct {
+	MaxPresses  int
+	MaxDuration time.Duration
+	// StopAt is a wall-clock time of day in the local zone. A time already
+	// past when the run starts means that time tomorrow.
+	StopAt string
+}
+
+// deadline returns when a task started at start must end, or the zero time.
+// MaxDuration does not count the time the task has spent paused; StopAt is a
+// time of day and does not move.
+func (l Limits) deadline(start time.Time, paused time.Duration) time.Time {
+	var deadline time.Time
+	if l.MaxDuration > 0 {
+		deadline = start.Add(l.MaxDuration + paused)
+	}
+	if at, ok := nextStopAt(l.StopAt, start); ok && (deadline.IsZero() || at.Before(deadline)) {
+		deadline = at
+	}
+	return deadline
+}
+
+func nextStopAt(value string, now time.Time) (time.Time, bool) {
+	clock, err := time.Parse(stopAtLayout, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	at := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !at.After(now) {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at, true
+}
+
+func entryLimits(e *KeyEntry) Limits {
+	return Limits{
+		MaxPresses:  e.MaxPresses,
+		MaxDuration: time.Duration(e.MaxDurationMS) * time.Millisecond,
+		StopAt:      e.StopAt,
+	}
+}
+
+// StopSummary describes the entry's stop conditions for list views, or
+// returns "" when it runs until stopped.
+func (e *KeyEntry) StopSummary() string {
+	var parts []string
+	if e.MaxPresses > 0 {
+		parts = append(parts, fmt.Sprintf("%d presses", e.MaxPresses))
+	}
+	if e.MaxDurationMS > 0 {
+		parts = append(parts, (time.Duration(e.MaxDurationMS) * time.Millisecond).String())
+	}
+	if e.StopAt != "" {
+		parts = append(parts, "at "+e.StopAt)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseDurationMS reads a duration field in milliseconds ("1500") or as a Go
+// duration ("1.5s", "10m"). An empty value is zero.
+func ParseDurationMS(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+		return ms, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return int(d / time.Millisecond), nil
+	}
+	return 0, fmt.Errorf("invalid duration %q: enter ms or a duration such as 1.5s or 10m", value)
+}
+
+// FormatDurationMS is the inverse of ParseDurationMS.
+func FormatDurationMS(ms int) string {
+	if ms <= 0 {
+		return ""
+	}
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+// ParseStopAt checks a time of day written as HH:MM and returns it
+// normalized. An empty value means no end time.
+func ParseStopAt(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	clock, err := time.Parse(stopAtLayout, value)
+	if err != nil {
+		return "", fmt.Errorf("invalid end time %q: enter a time such as 17:30", value)
+	}
+	return clock.Format(stopAtLayout), nil
+}
+
+// ParseMaxPresses reads a press count. An empty value means no limit.
+func ParseMaxPresses(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("invalid press count %q", value)
+	}
+	return count, nil
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimitsDeadline(t *testing.T) {
+	start := time.Date(2026, 5, 1, 17, 0, 0, 0, time.Local)
+	tests := []struct {
+		name   string
+		limits Limits
+		paused time.Duration
+		want   time.Time
+	}{
+		{name: "none", limits: Limits{MaxPresses: 3}},
+		{name: "duration", limits: Limits{MaxDuration: time.Minute}, want: start.Add(time.Minute)},
+		{name: "stop at later today", limits: Limits{StopAt: "17:30"}, want: start.Add(30 * time.Minute)},
+		{name: "stop at already past", limits: Limits{StopAt: "16:00"}, want: start.Add(23 * time.Hour)},
+		{name: "stop at now", limits: Limits{StopAt: "17:00"}, want: start.AddDate(0, 0, 1)},
+		{name: "earlier of both", limits: Limits{MaxDuration: time.Hour, StopAt: "17:30"}, want: start.Add(30 * time.Minute)},
+		{name: "duration first", limits: Limits{MaxDuration: time.Minute, StopAt: "17:30"}, want: start.Add(time.Minute)},
+		{name: "duration after a pause", limits: Limits{MaxDuration: time.Minute}, paused: time.Minute, want: start.Add(2 * time.Minute)},
+		{name: "stop at ignores pauses", limits: Limits{StopAt: "17:30"}, paused: time.Hour, want: start.Add(30 * time.Minute)},
+	}
+	for _, tt := range tests {
+		if got := tt.limits.deadline(start, tt.paused); !got.Equal(tt.want) {
+			t.Errorf("%s: deadline = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStopSummary(t *testing.T) {
+	tests := []struct {
+		entry KeyEntry
+		want  string
+	}{
+		{entry: KeyEntry{}, want: ""},
+		{entry: KeyEntry{MaxPresses: 5}, want: "5 presses"},
+		{entry: KeyEntry{MaxPresses: 5, MaxDurationMS: 90000, StopAt: "17:30"}, want: "5 presses, 1m30s, at 17:30"},
+	}
+	for _, tt := range tests {
+		if got := tt.entry.StopSummary(); got != tt.want {
+			t.Errorf("StopSummary(%+v) = %q, want %q", tt.entry, got, tt.want)
+		}
+	}
+}
+
+func TestParseDurationMS(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "1500", want: 1500},
+		{value: " 1.5s ", want: 1500},
+		{value: "10m", want: 600000},
+		{value: "-5", wantErr: true},
+		{value: "-1s", wantErr: true},
+		{value: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDurationMS(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseDurationMS(%q) = %d, %v; want %d, error %t", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+	for ms, want := range map[int]string{0: "", 1500: "1.5s", 600000: "10m0s"} {
+		if got := FormatDurationMS(ms); got != want {
+			t.Errorf("FormatDurationMS(%d) = %q, want %q", ms, got, want)
+		}
+	}
+}
+
+func TestParseStopAt(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: ""},
+		{value: "17:30", want: "17:30"},
+		{value: " 7:05 ", want: "07:05"},
+		{value: "25:00", wantErr: true},
+		{value: "5pm", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseStopAt(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseStopAt(%q) = %q, %v; want %q, error %t", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseMaxPresses(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: " 12 ", want: 12},
+		{value: "-1", wantErr: true},
+		{value: "many", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMaxPresses(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMaxPresses(%q) = %d, %v; want %d, error %t", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
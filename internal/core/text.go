@@ -0,0 +1,170 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TextTask is what a text entry types on each run; see KeyEntry.Text.
+type TextTask struct {
+	template *template.Template
+	// File, when set, is read on every run instead.
+	File string
+}
+
+// TextData is what a text entry's template can use: {{.N}} is the run
+// number, counting from 1, {{.Now.Format "15:04"}} the time and
+// {{.Random 1 6}} a random number between the two, inclusive.
+type TextData struct {
+	N   int
+	Now time.Time
+	rng *rand.Rand
+}
+
+func (d TextData) Random(min, max int) int {
+	if max <= min {
+		return min
+	}
+	return min + d.rng.IntN(max-min+1)
+}
+
+func buildText(entry *KeyEntry) (*TextTask, error) {
+	if entry.TextFile != "" {
+		if _, err := readTextFile(entry.TextFile); err != nil {
+			return nil, err
+		}
+		return &TextTask{File: entry.TextFile}, nil
+	}
+	tmpl, err := template.New("text").Option("missingkey=error").Parse(UnescapeText(entry.Text))
+	if err != nil {
+		return nil, err
+	}
+	return &TextTask{template: tmpl}, nil
+}
+
+// CheckText reports why the text dialogs cannot save entry: it has neither
+// text nor a file, its template does not parse or its file cannot be read.
+func CheckText(entry *KeyEntry) error {
+	if entry.Text == "" && entry.TextFile == "" {
+		return errors.New("enter text to type or a file to read it from")
+	}
+	_, err := buildText(entry)
+	return err
+}
+
+// render returns the text to type on run n.
+func (t *TextTask) render(n int, rng *rand.Rand) (string, error) {
+	if t.File != "" {
+		return readTextFile(t.File)
+	}
+	var text strings.Builder
+	if err := t.template.Execute(&text, TextData{N: n, Now: time.Now(), rng: rng}); err != nil {
+		return "", err
+	}
+	return text.String(), nil
+}
+
+// readTextFile reads a text file to type, without the line break that ends
+// most files.
+func readTextFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	text := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(text, "\r"), nil
+}
+
+// UnescapeText replaces the \n, \t and \\ escapes of typed text. Other
+// backslashes are kept as they are.
+func UnescapeText(text string) string {
+	if !strings.Contains(text, `\`) {
+		return text
+	}
+	var unescaped strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) {
+			switch text[i+1] {
+			case 'n':
+				unescaped.WriteByte('\n')
+				i++
+				continue
+			case 't':
+				unescaped.WriteByte('\t')
+				i++
+				continue
+			case '\\':
+				unescaped.WriteByte('\\')
+				i++
+				continue
+			}
+		}
+		unescaped.WriteByte(text[i])
+	}
+	return unescaped.String()
+}
+
+// typeRune types one character with pressMu held. Line breaks and tabs are
+// pressed as ENTER and TAB, which apps handle more reliably than the
+// characters themselves; a carriage return before a line break is dropped.
+func (r *Runner) typeRune(char rune) error {
+	switch char {
+	case '\n':
+		return Press(r.injector, KeyTask{Key: KeyEnter}, 0)
+	case '\t':
+		return Press(r.injector, KeyTask{Key: KeyTab}, 0)
+	case '\r':
+		return nil
+	default:
+		return r.injector.TypeRune(char)
+	}
+}
+
+// typeText types text. Without a delay the whole text is one press that
+// other tasks cannot interleave with; with one, other tasks may press between
+// characters, and Pause and Stop take effect there. ok is false if the run
+// was stopped midway.
+func (r *Runner) typeText(text string, delay time.Duration, stopCh <-chan struct{}, held *heldKeys) (paused time.Duration, ok bool, err error) {
+	if delay <= 0 {
+		r.pressMu.Lock()
+		defer r.pressMu.Unlock()
+		for _, char := range text {
+			if err := r.typeRune(char); err != nil {
+				return 0, true, err
+			}
+		}
+		return 0, true, nil
+	}
+
+	for i, char := range []rune(text) {
+		if i > 0 {
+			pausedFor, ok := r.sleep(delay, stopCh, held)
+			paused += pausedFor
+			if !ok {
+				return paused, false, nil
+			}
+		}
+		r.pressMu.Lock()
+		err := r.typeRune(char)
+		r.pressMu.Unlock()
+		if err != nil {
+			return paused, true, err
+		}
+	}
+	return paused, true, nil
+}
+
+// runText types a text entry's text as rendered for run n.
+func (r *Runner) runText(task KeyTask, rng *rand.Rand, n int, stopCh <-chan struct{}) (completed bool, paused time.Duration, err error) {
+	text, err := task.Text.render(n, rng)
+	if err != nil {
+		return true, 0, fmt.Errorf("text: %w", err)
+	}
+	paused, completed, err = r.typeText(text, task.TypeDelay, stopCh, nil)
+	return completed, paused, err
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnescapeText(t *testing.T) {
+	tests := map[string]string{
+		`hello`:          "hello",
+		`a\nb\tc`:        "a\nb\tc",
+		`C:\\temp`:       `C:\temp`,
+		`C:\temp`:        "C:\temp",
+		`keep \x and \`:  `keep \x and \`,
+		`emoji 😀\n`:      "emoji 😀\n",
+		`\\n is literal`: `\n is literal`,
+	}
+	for text, want := range tests {
+		if got := UnescapeText(text); got != want {
+			t.Errorf("UnescapeText(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestBuildTasksText(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(file, []byte("line one\nline two\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entries := []*KeyEntry{
+		{Key: "Greeting", IntervalMS: 100, Enabled: true, Text: `hi {{.N}}, {{.Random 3 3}}\n`},
+		{IntervalMS: 100, Enabled: true, TextFile: file},
+		{Key: "Broken", IntervalMS: 100, Enabled: true, Text: "{{.N"},
+		{Key: "Missing", IntervalMS: 100, Enabled: true, TextFile: filepath.Join(t.TempDir(), "missing.txt")},
+	}
+	tasks, errs := BuildTasks(entries, newFakeInjector())
+	if len(tasks) != 2 || len(errs) != 2 {
+		t.Fatalf("BuildTasks built %d tasks with errors %q, want 2 of each", len(tasks), errs)
+	}
+	if !strings.HasPrefix(errs[0], "Broken (text): ") || !strings.HasPrefix(errs[1], "Missing (text): ") {
+		t.Errorf("errors = %q, want them to name the entries", errs)
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i, want := range []string{"hi 7, 3\n", "line one\nline two"} {
+		if got, err := tasks[i].Text.render(7, rng); err != nil || got != want {
+			t.Errorf("task %d renders %q, %v; want %q", i, got, err, want)
+		}
+	}
+}
+
+func TestCheckText(t *testing.T) {
+	tests := []struct {
+		entry   KeyEntry
+		wantErr bool
+	}{
+		{KeyEntry{Text: "hello {{.N}}"}, false},
+		{KeyEntry{}, true},
+		{KeyEntry{Text: "{{.Missing"}, true},
+		{KeyEntry{TextFile: filepath.Join(t.TempDir(), "missing.txt")}, true},
+	}
+	for _, tt := range tests {
+		if err := CheckText(&tt.entry); (err != nil) != tt.wantErr {
+			t.Errorf("CheckText(%+v) = %v, want error %t", tt.entry, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRunnerTypesText(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	tasks, _ := BuildTasks([]*KeyEntry{{IntervalMS: 5, Enabled: true, Text: `a\tb\n{{.N}}`, TypeDelayMS: 10}}, injector)
+	start := time.Now()
+	runner.Start(tasks)
+	waitFor(t, "the text to be typed", func() bool { return injector.count("1") > 0 })
+	runner.Stop()
+
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("typed 5 characters in %v, want 10ms between them", elapsed)
+	}
+	want := []string{"a", "+TAB", "-TAB", "b", "+ENTER", "-ENTER", "1"}
+	if got := injector.log(); !slices.Equal(got[:len(want)], want) {
+		t.Errorf("events = %q, want them to start with %q", got, want)
+	}
+}
+
+func TestRunnerStopsWhileTyping(t *testing.T) {
+	injector := newFakeInjector()
+	runner := NewRunner(injector)
+	runner.Start([]KeyTask{{Text: &TextTask{File: writeText(t, "abcdef")}, TypeDelay: time.Second, Interval: time.Millisecond}})
+	waitFor(t, "the first character", func() bool { return injector.count("a") > 0 })
+
+	start := time.Now()
+	runner.Stop()
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Stop took %v while typing", elapsed)
+	}
+	if injector.count("b") > 0 {
+		t.Error("typing went on after Stop")
+	}
+}
+
+func writeText(t *testing.T, text string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "text.txt")
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
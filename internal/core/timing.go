@@ -0,0 +1,151 @@
+package core
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Jitter distributions accepted in KeyEntry.JitterDistribution.
+const (
+	DistributionUniform  = "uniform"
+	DistributionGaussian = "gaussian"
+)
+
+// Timing randomizes when and how long a task's key is pressed so the presses
+// do not land on an exact, machine-like beat.
+type Timing struct {
+	// Jitter is the largest amount each interval is shortened or lengthened.
+	Jitter time.Duration
+	// Gaussian clusters jitter around the interval (standard deviation
+	// Jitter/2, clamped to ±Jitter) instead of spreading it evenly.
+	Gaussian bool
+	// HoldMin and HoldMax bound how long the key is held down. Zero taps it.
+	HoldMin time.Duration
+	HoldMax time.Duration
+}
+
+func (t Timing) nextInterval(rng *rand.Rand, interval time.Duration) time.Duration {
+	if t.Jitter <= 0 {
+		return interval
+	}
+	var offset float64
+	if t.Gaussian {
+		offset = rng.NormFloat64() / 2
+		offset = max(-1, min(1, offset))
+	} else {
+		offset = rng.Float64()*2 - 1
+	}
+	return max(time.Millisecond, interval+time.Duration(offset*float64(t.Jitter)))
+}
+
+func (t Timing) hold(rng *rand.Rand) time.Duration {
+	if t.HoldMax <= t.HoldMin {
+		return t.HoldMin
+	}
+	return t.HoldMin + time.Duration(rng.Int64N(int64(t.HoldMax-t.HoldMin)+1))
+}
+
+// entryTiming converts the entry's timing fields. Percent jitter is only
+// used when no millisecond jitter is set.
+func entryTiming(e *KeyEntry) Timing {
+	jitterMS := e.JitterMS
+	if jitterMS == 0 && e.JitterPercent > 0 {
+		jitterMS = e.IntervalMS * e.JitterPercent / 100
+	}
+	return Timing{
+		Jitter:   time.Duration(jitterMS) * time.Millisecond,
+		Gaussian: e.JitterDistribution == DistributionGaussian,
+		HoldMin:  time.Duration(e.HoldMinMS) * time.Millisecond,
+		HoldMax:  time.Duration(e.HoldMaxMS) * time.Millisecond,
+	}
+}
+
+// TimingSummary describes the entry's offset, jitter and hold for list
+// views, or returns "" when it is pressed on an exact beat.
+func (e *KeyEntry) TimingSummary() string {
+	var parts []string
+	if e.OffsetMS > 0 {
+		parts = append(parts, fmt.Sprintf("offset %d ms", e.OffsetMS))
+	}
+	if jitter := FormatJitter(e.JitterMS, e.JitterPercent); jitter != "" {
+		jitter = "±" + jitter
+		if e.JitterMS > 0 {
+			jitter += " ms"
+		}
+		if e.JitterDistribution == DistributionGaussian {
+			jitter += " gaussian"
+		}
+		parts = append(parts, jitter)
+	}
+	if hold := FormatHold(e.HoldMinMS, e.HoldMaxMS); hold != "" {
+		parts = append(parts, "hold "+hold+" ms")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseJitter reads the jitter field of the add dialogs: "50" means ±50 ms
+// and "10%" means ±10% of the interval. An empty value means no jitter.
+func ParseJitter(value string) (ms, percent int, err error) {
+	value = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "±"))
+	if value == "" {
+		return 0, 0, nil
+	}
+	if number, ok := strings.CutSuffix(value, "%"); ok {
+		percent, err = strconv.Atoi(strings.TrimSpace(number))
+		if err != nil || percent < 0 || percent > 100 {
+			return 0, 0, fmt.Errorf("invalid jitter %q: enter ms or a percentage up to 100%%", value)
+		}
+		return 0, percent, nil
+	}
+	ms, err = strconv.Atoi(strings.TrimSuffix(value, "ms"))
+	if err != nil || ms < 0 {
+		return 0, 0, fmt.Errorf("invalid jitter %q: enter ms or a percentage up to 100%%", value)
+	}
+	return ms, 0, nil
+}
+
+// FormatJitter is the inverse of ParseJitter.
+func FormatJitter(ms, percent int) string {
+	switch {
+	case ms > 0:
+		return strconv.Itoa(ms)
+	case percent > 0:
+		return strconv.Itoa(percent) + "%"
+	default:
+		return ""
+	}
+}
+
+// ParseHold reads the hold field of the add dialogs: "40" holds for 40 ms
+// and "30-80" holds for a random time in that range. An empty value taps.
+func ParseHold(value string) (minMS, maxMS int, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, 0, nil
+	}
+	low, high, isRange := strings.Cut(value, "-")
+	minMS, err = strconv.Atoi(strings.TrimSpace(low))
+	maxMS = minMS
+	if err == nil && isRange {
+		maxMS, err = strconv.Atoi(strings.TrimSpace(high))
+	}
+	if err != nil || minMS < 0 || maxMS < minMS {
+		return 0, 0, fmt.Errorf("invalid hold %q: enter ms or a range such as 30-80", value)
+	}
+	return minMS, maxMS, nil
+}
+
+// FormatHold is the inverse of ParseHold.
+func FormatHold(minMS, maxMS int) string {
+	switch {
+	case maxMS > minMS:
+		return fmt.Sprintf("%d-%d", minMS, maxMS)
+	case minMS > 0:
+		return strconv.Itoa(minMS)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestParseJitter(t *testing.T) {
+	tests := []struct {
+		input   string
+		ms      int
+		percent int
+		wantErr bool
+	}{
+		{input: "", ms: 0},
+		{input: "50", ms: 50},
+		{input: "±50", ms: 50},
+		{input: "50ms", ms: 50},
+		{input: "10%", percent: 10},
+		{input: " ± 10 % ", percent: 10},
+		{input: "100%", percent: 100},
+
+		{input: "101%", wantErr: true},
+		{input: "-5", wantErr: true},
+		{input: "fast", wantErr: true},
+	}
+	for _, tt := range tests {
+		ms, percent, err := ParseJitter(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseJitter(%q) = %d, %d, want an error", tt.input, ms, percent)
+			}
+			continue
+		}
+		if err != nil || ms != tt.ms || percent != tt.percent {
+			t.Errorf("ParseJitter(%q) = %d, %d, %v, want %d, %d", tt.input, ms, percent, err, tt.ms, tt.percent)
+			continue
+		}
+		if again, _, _ := ParseJitter(FormatJitter(ms, percent)); again != ms {
+			t.Errorf("FormatJitter(%d, %d) does not parse back", ms, percent)
+		}
+	}
+}
+
+func TestParseHold(t *testing.T) {
+	tests := []struct {
+		input    string
+		min, max int
+		format   string
+		wantErr  bool
+	}{
+		{input: "", format: ""},
+		{input: "40", min: 40, max: 40, format: "40"},
+		{input: "30-80", min: 30, max: 80, format: "30-80"},
+		{input: " 30 - 80 ", min: 30, max: 80, format: "30-80"},
+		{input: "30-30", min: 30, max: 30, format: "30"},
+
+		{input: "80-30", wantErr: true},
+		{input: "-5", wantErr: true},
+		{input: "long", wantErr: true},
+	}
+	for _, tt := range tests {
+		low, high, err := ParseHold(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseHold(%q) = %d, %d, want an error", tt.input, low, high)
+			}
+			continue
+		}
+		if err != nil || low != tt.min || high != tt.max {
+			t.Errorf("ParseHold(%q) = %d, %d, %v, want %d, %d", tt.input, low, high, err, tt.min, tt.max)
+		}
+		if got := FormatHold(low, high); got != tt.format {
+			t.Errorf("FormatHold(%d, %d) = %q, want %q", low, high, got, tt.format)
+		}
+	}
+}
+
+func TestTimingSummary(t *testing.T) {
+	tests := []struct {
+		entry KeyEntry
+		want  string
+	}{
+		{entry: KeyEntry{}, want: ""},
+		{entry: KeyEntry{JitterMS: 50}, want: "±50 ms"},
+		{entry: KeyEntry{JitterPercent: 10, JitterDistribution: DistributionGaussian}, want: "±10% gaussian"},
+		{entry: KeyEntry{HoldMinMS: 30, HoldMaxMS: 80}, want: "hold 30-80 ms"},
+		{entry: KeyEntry{JitterMS: 5, HoldMinMS: 40}, want: "±5 ms, hold 40 ms"},
+		{entry: KeyEntry{OffsetMS: 250, JitterMS: 5}, want: "offset 250 ms, ±5 ms"},
+	}
+	for _, tt := range tests {
+		if got := tt.entry.TimingSummary(); got != tt.want {
+			t.Errorf("TimingSummary(%+v) = %q, want %q", tt.entry, got, tt.want)
+		}
+	}
+}
+
+func TestEntryTiming(t *testing.T) {
+	timing := entryTiming(&KeyEntry{IntervalMS: 2000, JitterPercent: 10, HoldMinMS: 5, HoldMaxMS: 9})
+	want := Timing{Jitter: 200 * time.Millisecond, HoldMin: 5 * time.Millisecond, HoldMax: 9 * time.Millisecond}
+	if timing != want {
+		t.Errorf("percent jitter = %+v, want %+v", timing, want)
+	}
+	timing = entryTiming(&KeyEntry{IntervalMS: 2000, JitterMS: 30, JitterPercent: 10, JitterDistribution: DistributionGaussian})
+	if timing.Jitter != 30*time.Millisecond || !timing.Gaussian {
+		t.Errorf("ms jitter = %+v, want 30ms gaussian", timing)
+	}
+}
+
+func TestTimingDrawsStayInRange(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	rng := rand.New(rand.NewPCG(1, 2))
+	for _, timing := range []Timing{
+		{Jitter: 20 * time.Millisecond},
+		{Jitter: 20 * time.Millisecond, Gaussian: true},
+	} {
+		for range 1000 {
+			if got := timing.nextInterval(rng, interval); got < 80*time.Millisecond || got > 120*time.Millisecond {
+				t.Fatalf("%+v drew %v, outside 100ms ±20ms", timing, got)
+			}
+		}
+	}
+	if got := (Timing{Jitter: time.Second}).nextInterval(rng, 10*time.Millisecond); got < time.Millisecond {
+		t.Errorf("jitter larger than the interval drew %v", got)
+	}
+	if got := (Timing{}).nextInterval(rng, interval); got != interval {
+		t.Errorf("no jitter drew %v", got)
+	}
+
+	hold := Timing{HoldMin: 30 * time.Millisecond, HoldMax: 80 * time.Millisecond}
+	for range 1000 {
+		if got := hold.hold(rng); got < hold.HoldMin || got > hold.HoldMax {
+			t.Fatalf("hold drew %v, outside 30-80ms", got)
+		}
+	}
+	if got := (Timing{HoldMin: 40 * time.Millisecond}).hold(rng); got != 40*time.Millisecond {
+		t.Errorf("fixed hold drew %v", got)
+	}
+}
@@ -0,0 +1,358 @@
+// Package profile saves and loads key entry lists as named profiles in the
+// user config directory. Files are YAML or JSON, chosen by extension, and
+// carry a format version so older profiles keep loading after upgrades.
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"autokeypress/internal/core"
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the file format written by Save. Bump it when the layout
+// changes, or when a field is added that an older app would ignore and so
+// press the wrong keys, and teach migrate how to read the previous one.
+// Version 2 added jitter, hold times, steps, stop conditions, offsets and
+// text.
+const CurrentVersion = 2
+
+const (
+	appDirName    = "autokeypress"
+	profileSubdir = "profiles"
+	lastFileName  = "last-profile"
+	defaultExt    = ".yaml"
+)
+
+type Profile struct {
+	Name    string
+	Path    string
+	Entries []*core.KeyEntry
+}
+
+type fileFormat struct {
+	Version int     `json:"version" yaml:"version"`
+	Name    string  `json:"name,omitempty" yaml:"name,omitempty"`
+	Entries []Entry `json:"entries" yaml:"entries"`
+}
+
+// Entry is one key entry as stored in profile files. The control API uses
+// the same layout.
+type Entry struct {
+	Key        string `json:"key" yaml:"key"`
+	IntervalMS int    `json:"interval_ms" yaml:"interval_ms"`
+	Enabled    *bool  `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	OffsetMS   int    `json:"offset_ms,omitempty" yaml:"offset_ms,omitempty"`
+
+	JitterMS           int    `json:"jitter_ms,omitempty" yaml:"jitter_ms,omitempty"`
+	JitterPercent      int    `json:"jitter_percent,omitempty" yaml:"jitter_percent,omitempty"`
+	JitterDistribution string `json:"jitter_distribution,omitempty" yaml:"jitter_distribution,omitempty"`
+	HoldMinMS          int    `json:"hold_min_ms,omitempty" yaml:"hold_min_ms,omitempty"`
+	HoldMaxMS          int    `json:"hold_max_ms,omitempty" yaml:"hold_max_ms,omitempty"`
+
+	Steps []Step `json:"steps,omitempty" yaml:"steps,omitempty"`
+
+	Text        string `json:"text,omitempty" yaml:"text,omitempty"`
+	TextFile    string `json:"text_file,omitempty" yaml:"text_file,omitempty"`
+	TypeDelayMS int    `json:"type_delay_ms,omitempty" yaml:"type_delay_ms,omitempty"`
+
+	MaxPresses    int    `json:"max_presses,omitempty" yaml:"max_presses,omitempty"`
+	MaxDurationMS int    `json:"max_duration_ms,omitempty" yaml:"max_duration_ms,omitempty"`
+	StopAt        string `json:"stop_at,omitempty" yaml:"stop_at,omitempty"`
+}
+
+// Step sets exactly one of key, wait_ms, type, down, up and hold, which
+// decides the step kind; hold also takes hold_ms.
+type Step struct {
+	Key    string `json:"key,omitempty" yaml:"key,omitempty"`
+	WaitMS *int   `json:"wait_ms,omitempty" yaml:"wait_ms,omitempty"`
+	Type   string `json:"type,omitempty" yaml:"type,omitempty"`
+	Down   string `json:"down,omitempty" yaml:"down,omitempty"`
+	Up     string `json:"up,omitempty" yaml:"up,omitempty"`
+	Hold   string `json:"hold,omitempty" yaml:"hold,omitempty"`
+	HoldMS int    `json:"hold_ms,omitempty" yaml:"hold_ms,omitempty"`
+}
+
+// Dir returns the directory holding saved profiles, creating it if needed.
+func Dir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(base, appDirName, profileSubdir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// PathFor returns where the profile called name is stored.
+func PathFor(name string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+defaultExt), nil
+}
+
+// List returns the names of the saved profiles, sorted.
+func List() ([]string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		if file.IsDir() || !isProfileFile(file.Name()) {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(file.Name(), filepath.Ext(file.Name())))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Load reads a profile file. A bare name (no extension or directory) is
+// looked up in Dir.
+func Load(path string) (*Profile, error) {
+	if filepath.Base(path) == path && filepath.Ext(path) == "" {
+		named, err := PathFor(path)
+		if err != nil {
+			return nil, err
+		}
+		path = named
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file fileFormat
+	if isJSON(path) {
+		err = json.Unmarshal(data, &file)
+	} else {
+		err = yaml.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if err := migrate(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	profile := &Profile{
+		Name: file.Name,
+		Path: path,
+	}
+	if profile.Name == "" {
+		profile.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	for i, entry := range file.Entries {
+		loaded, err := entry.KeyEntry()
+		if err != nil {
+			return nil, fmt.Errorf("%s: entry %d: %w", filepath.Base(path), i+1, err)
+		}
+		profile.Entries = append(profile.Entries, loaded)
+	}
+	return profile, nil
+}
+
+// Save writes p to p.Path, or to Dir under p.Name when Path is empty, and
+// fills in whichever of the two was missing.
+func Save(p *Profile) error {
+	if p.Path == "" {
+		if p.Name == "" {
+			return fmt.Errorf("profile has no name")
+		}
+		path, err := PathFor(p.Name)
+		if err != nil {
+			return err
+		}
+		p.Path = path
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(p.Path), filepath.Ext(p.Path))
+	}
+
+	file := fileFormat{
+		Version: CurrentVersion,
+		Name:    p.Name,
+	}
+	for _, entry := range p.Entries {
+		file.Entries = append(file.Entries, NewEntry(entry))
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if isJSON(p.Path) {
+		data, err = json.MarshalIndent(file, "", "  ")
+	} else {
+		data, err = yaml.Marshal(file)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p.Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(p.Path, data, 0o644)
+}
+
+// LoadLast loads the profile used most recently, if any.
+func LoadLast() (*Profile, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(dir), lastFileName))
+	if err != nil {
+		return nil, err
+	}
+	return Load(strings.TrimSpace(string(data)))
+}
+
+// RememberLast records path as the profile to load on the next launch.
+func RememberLast(path string) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(filepath.Dir(dir), lastFileName), []byte(abs+"\n"), 0o644)
+}
+
+// NewEntry converts entry to the file layout.
+func NewEntry(entry *core.KeyEntry) Entry {
+	enabled := entry.Enabled
+	return Entry{
+		Key:                entry.Key,
+		IntervalMS:         entry.IntervalMS,
+		Enabled:            &enabled,
+		OffsetMS:           entry.OffsetMS,
+		JitterMS:           entry.JitterMS,
+		JitterPercent:      entry.JitterPercent,
+		JitterDistribution: entry.JitterDistribution,
+		HoldMinMS:          entry.HoldMinMS,
+		HoldMaxMS:          entry.HoldMaxMS,
+		Steps:              saveSteps(entry.Steps),
+		Text:               entry.Text,
+		TextFile:           entry.TextFile,
+		TypeDelayMS:        entry.TypeDelayMS,
+		MaxPresses:         entry.MaxPresses,
+		MaxDurationMS:      entry.MaxDurationMS,
+		StopAt:             entry.StopAt,
+	}
+}
+
+// KeyEntry converts e back to a key entry. A missing enabled field means
+// enabled.
+func (e Entry) KeyEntry() (*core.KeyEntry, error) {
+	switch e.JitterDistribution {
+	case "", core.DistributionUniform, core.DistributionGaussian:
+	default:
+		return nil, fmt.Errorf("unknown jitter_distribution %q", e.JitterDistribution)
+	}
+	return &core.KeyEntry{
+		Key:                e.Key,
+		IntervalMS:         e.IntervalMS,
+		Enabled:            e.Enabled == nil || *e.Enabled,
+		OffsetMS:           e.OffsetMS,
+		JitterMS:           e.JitterMS,
+		JitterPercent:      e.JitterPercent,
+		JitterDistribution: e.JitterDistribution,
+		HoldMinMS:          e.HoldMinMS,
+		HoldMaxMS:          e.HoldMaxMS,
+		Steps:              loadSteps(e.Steps),
+		Text:               e.Text,
+		TextFile:           e.TextFile,
+		TypeDelayMS:        e.TypeDelayMS,
+		MaxPresses:         e.MaxPresses,
+		MaxDurationMS:      e.MaxDurationMS,
+		StopAt:             e.StopAt,
+	}, nil
+}
+
+func loadSteps(steps []Step) []core.Step {
+	var loaded []core.Step
+	for _, step := range steps {
+		switch {
+		case step.WaitMS != nil:
+			loaded = append(loaded, core.Step{Kind: core.StepWait, WaitMS: *step.WaitMS})
+		case step.Type != "":
+			loaded = append(loaded, core.Step{Kind: core.StepType, Text: step.Type})
+		case step.Down != "":
+			loaded = append(loaded, core.Step{Kind: core.StepDown, Key: step.Down})
+		case step.Up != "":
+			loaded = append(loaded, core.Step{Kind: core.StepUp, Key: step.Up})
+		case step.Hold != "":
+			loaded = append(loaded, core.Step{Kind: core.StepHold, Key: step.Hold, HoldMS: step.HoldMS})
+		default:
+			loaded = append(loaded, core.Step{Kind: core.StepKey, Key: step.Key})
+		}
+	}
+	return loaded
+}
+
+func saveSteps(steps []core.Step) []Step {
+	var saved []Step
+	for _, step := range steps {
+		switch step.Kind {
+		case core.StepWait:
+			wait := step.WaitMS
+			saved = append(saved, Step{WaitMS: &wait})
+		case core.StepType:
+			saved = append(saved, Step{Type: step.Text})
+		case core.StepDown:
+			saved = append(saved, Step{Down: step.Key})
+		case core.StepUp:
+			saved = append(saved, Step{Up: step.Key})
+		case core.StepHold:
+			saved = append(saved, Step{Hold: step.Key, HoldMS: step.HoldMS})
+		default:
+			saved = append(saved, Step{Key: step.Key})
+		}
+	}
+	return saved
+}
+
+// migrate upgrades file in place to CurrentVersion.
+func migrate(file *fileFormat) error {
+	switch {
+	case file.Version > CurrentVersion:
+		return fmt.Errorf("profile version %d is newer than this app supports (%d)", file.Version, CurrentVersion)
+	case file.Version <= 1:
+		// Hand-written files without a version use the current layout, and
+		// version 2 only added fields to version 1.
+		file.Version = CurrentVersion
+	}
+	return nil
+}
+
+func isJSON(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+func isProfileFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
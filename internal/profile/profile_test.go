@@ -0,0 +1,192 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"autokeypress/internal/core"
+)
+
+func testEntries() []*core.KeyEntry {
+	return []*core.KeyEntry{
+		{
+			Key: "CTRL+S", IntervalMS: 30000, Enabled: true, OffsetMS: 500,
+			JitterMS: 100, JitterDistribution: core.DistributionGaussian,
+			HoldMinMS: 20, HoldMaxMS: 80,
+			MaxPresses: 5, MaxDurationMS: 60000, StopAt: "17:30",
+		},
+		{Key: "F5", IntervalMS: 1000},
+		{Key: "é", IntervalMS: 250, Enabled: true, JitterPercent: 10, JitterDistribution: core.DistributionUniform},
+		{Key: "Greeting", IntervalMS: 5000, Enabled: true, Text: `hello {{.N}}\n`, TypeDelayMS: 30},
+		{IntervalMS: 60000, TextFile: "notes.txt"},
+		{
+			Key: "Sequence", IntervalMS: 2000, Enabled: true,
+			Steps: []core.Step{
+				{Kind: core.StepKey, Key: "CTRL+A"},
+				{Kind: core.StepWait, WaitMS: 0},
+				{Kind: core.StepType, Text: "hello"},
+				{Kind: core.StepDown, Key: "SHIFT"},
+				{Kind: core.StepHold, Key: "W", HoldMS: 2000},
+				{Kind: core.StepUp, Key: "SHIFT"},
+			},
+		},
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	for _, ext := range []string{".yaml", ".json"} {
+		t.Run(ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "farm"+ext)
+			saved := &Profile{Path: path, Entries: testEntries()}
+			if err := Save(saved); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			if saved.Name != "farm" {
+				t.Errorf("Save named the profile %q, want farm", saved.Name)
+			}
+
+			loaded, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if loaded.Name != "farm" || loaded.Path != path {
+				t.Errorf("loaded %q from %q", loaded.Name, loaded.Path)
+			}
+			if !reflect.DeepEqual(loaded.Entries, testEntries()) {
+				t.Errorf("entries = %+v, want %+v", loaded.Entries, testEntries())
+			}
+		})
+	}
+}
+
+func TestLoadFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		want    []*core.KeyEntry
+		wantErr string
+	}{
+		{
+			name: "enabled defaults to true",
+			file: "plain.yaml",
+			data: "entries:\n  - key: F5\n    interval_ms: 1000\n  - key: F6\n    interval_ms: 500\n    enabled: false\n",
+			want: []*core.KeyEntry{{Key: "F5", IntervalMS: 1000, Enabled: true}, {Key: "F6", IntervalMS: 500}},
+		},
+		{
+			name: "json",
+			file: "plain.JSON",
+			data: `{"version": 1, "name": "other", "entries": [{"key": "TAB", "interval_ms": 100}]}`,
+			want: []*core.KeyEntry{{Key: "TAB", IntervalMS: 100, Enabled: true}},
+		},
+		{
+			name: "jitter and hold",
+			file: "timing.yaml",
+			data: "entries:\n  - key: F5\n    interval_ms: 1000\n    jitter_percent: 5\n    hold_min_ms: 30\n",
+			want: []*core.KeyEntry{{Key: "F5", IntervalMS: 1000, Enabled: true, JitterPercent: 5, HoldMinMS: 30}},
+		},
+		{
+			name: "json steps",
+			file: "steps.json",
+			data: `{"version": 2, "entries": [{"key": "Sequence", "interval_ms": 100, "steps": [{"key": "TAB"}, {"wait_ms": 50}, {"type": "hi"}]}]}`,
+			want: []*core.KeyEntry{{
+				Key: "Sequence", IntervalMS: 100, Enabled: true,
+				Steps: []core.Step{
+					{Kind: core.StepKey, Key: "TAB"},
+					{Kind: core.StepWait, WaitMS: 50},
+					{Kind: core.StepType, Text: "hi"},
+				},
+			}},
+		},
+		{
+			name:    "unknown jitter distribution",
+			file:    "normal.yaml",
+			data:    "entries:\n  - key: F5\n    interval_ms: 1000\n  - key: F6\n    interval_ms: 1000\n    jitter_ms: 50\n    jitter_distribution: normal\n",
+			wantErr: `normal.yaml: entry 2: unknown jitter_distribution "normal"`,
+		},
+		{
+			name:    "newer version",
+			file:    "future.yaml",
+			data:    "version: 3\nentries: []\n",
+			wantErr: "profile version 3 is newer than this app supports (2)",
+		},
+		{
+			name:    "broken file",
+			file:    "broken.json",
+			data:    "{",
+			wantErr: "broken.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load error = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if !reflect.DeepEqual(loaded.Entries, tt.want) {
+				t.Errorf("entries = %+v, want %+v", loaded.Entries, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamedProfiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	if _, err := LoadLast(); err == nil {
+		t.Fatal("LoadLast succeeded before any profile was remembered")
+	}
+	if err := Save(&Profile{}); err == nil {
+		t.Error("Save accepted a profile with neither name nor path")
+	}
+
+	for _, name := range []string{"work", "games"} {
+		if err := Save(&Profile{Name: name, Entries: testEntries()[:1]}); err != nil {
+			t.Fatalf("Save %s: %v", name, err)
+		}
+	}
+	dir, err := Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	names, err := List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if want := []string{"games", "work"}; !slices.Equal(names, want) {
+		t.Errorf("List = %q, want %q", names, want)
+	}
+
+	work, err := Load("work")
+	if err != nil {
+		t.Fatalf("Load by name: %v", err)
+	}
+	if err := RememberLast(work.Path); err != nil {
+		t.Fatalf("RememberLast: %v", err)
+	}
+	last, err := LoadLast()
+	if err != nil {
+		t.Fatalf("LoadLast: %v", err)
+	}
+	if last.Name != "work" || !reflect.DeepEqual(last.Entries, testEntries()[:1]) {
+		t.Errorf("LoadLast = %q %+v", last.Name, last.Entries)
+	}
+}
@@ -0,0 +1,92 @@
+// Package settings stores app-wide preferences that do not belong to any one
+// profile, such as the global hotkeys and start delay, in the user config
+// directory.
+package settings
+
+import (
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	appDirName   = "autokeypress"
+	settingsFile = "settings.yaml"
+)
+
+// Default hotkeys, chosen to be unlikely to clash with the app being driven.
+const (
+	DefaultToggleHotkey = "CTRL+SHIFT+F12"
+	DefaultStopHotkey   = "CTRL+SHIFT+F11"
+)
+
+type Settings struct {
+	// ToggleHotkey starts the runner when idle and stops it when running.
+	ToggleHotkey string `yaml:"toggle_hotkey"`
+	// StopHotkey always stops every task. It is registered separately so it
+	// still works if the toggle was pressed by mistake.
+	StopHotkey string `yaml:"stop_hotkey"`
+	// StartDelaySeconds counts down before the first press so there is time
+	// to switch to the target window.
+	StartDelaySeconds int `yaml:"start_delay_seconds"`
+	// StopAfterFailures stops a key once that many of its presses in a row
+	// have failed. Zero keeps failing keys running.
+	StopAfterFailures int `yaml:"stop_after_failures"`
+	// ControlAPI serves the local control API while the window is open. It
+	// is off unless asked for.
+	ControlAPI bool `yaml:"control_api"`
+}
+
+// Defaults returns the settings used before anything is saved.
+func Defaults() Settings {
+	return Settings{
+		ToggleHotkey: DefaultToggleHotkey,
+		StopHotkey:   DefaultStopHotkey,
+	}
+}
+
+// Load reads the saved settings. A missing file yields Defaults; fields left
+// out of the file keep their default value, and an empty hotkey disables it.
+func Load() (Settings, error) {
+	s := Defaults()
+	path, err := filePath()
+	if err != nil {
+		return s, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return Defaults(), err
+	}
+	return s, nil
+}
+
+// Save writes s to the config directory.
+func Save(s Settings) error {
+	path, err := filePath()
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func filePath() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, appDirName, settingsFile), nil
+}
@@ -0,0 +1,98 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMissingFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if s != Defaults() {
+		t.Errorf("Load = %+v, want the defaults %+v", s, Defaults())
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	want := Settings{
+		ToggleHotkey:      "ALT+F9",
+		StopHotkey:        "",
+		StartDelaySeconds: 3,
+		StopAfterFailures: 5,
+		ControlAPI:        true,
+	}
+	if err := Save(want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got != want {
+		t.Errorf("Load = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Settings
+		wantErr bool
+	}{
+		{
+			name: "left out fields keep their defaults",
+			data: "toggle_hotkey: F9\n",
+			want: Settings{ToggleHotkey: "F9", StopHotkey: DefaultStopHotkey},
+		},
+		{
+			name: "an empty hotkey disables it",
+			data: "toggle_hotkey: \"\"\nstop_hotkey: F10\n",
+			want: Settings{StopHotkey: "F10"},
+		},
+		{
+			name: "start delay",
+			data: "start_delay_seconds: 5\n",
+			want: Settings{ToggleHotkey: DefaultToggleHotkey, StopHotkey: DefaultStopHotkey, StartDelaySeconds: 5},
+		},
+		{
+			name:    "broken file",
+			data:    "toggle_hotkey: [\n",
+			want:    Defaults(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			path, err := filePath()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load error = %v, want error %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Load = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+//go:build darwin || (linux && gui)
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"autokeypress/internal/core"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// entryRow is one line of the key list. The enabled flag, key and interval
+// are edited in place, like the Windows table; the rest of the entry and its
+// live statistics are shown as text. A typed key or interval applies when
+// Enter is pressed.
+type entryRow struct {
+	widget.BaseWidget
+	enabled  *widget.Check
+	key      *widget.Entry
+	interval *widget.Entry
+	summary  *widget.Label
+
+	entry *core.KeyEntry
+	// onEdit is called with the old entry and the edited copy that is to
+	// replace it in the list, onInvalid with a message when a typed value
+	// was rejected and the field reverted.
+	onEdit    func(old, entry *core.KeyEntry)
+	onInvalid func(message string)
+}
+
+func newEntryRow(onEdit func(old, entry *core.KeyEntry), onInvalid func(string)) *entryRow {
+	r := &entryRow{
+		enabled:   widget.NewCheck("", nil),
+		key:       widget.NewEntry(),
+		interval:  widget.NewEntry(),
+		summary:   widget.NewLabel(""),
+		onEdit:    onEdit,
+		onInvalid: onInvalid,
+	}
+	r.interval.SetPlaceHolder("ms")
+	r.enabled.OnChanged = r.setEnabled
+	r.key.OnSubmitted = r.setKey
+	r.interval.OnSubmitted = r.setInterval
+	r.ExtendBaseWidget(r)
+	return r
+}
+
+func (r *entryRow) CreateRenderer() fyne.WidgetRenderer {
+	height := r.key.MinSize().Height
+	fields := container.NewHBox(
+		r.enabled,
+		container.NewGridWrap(fyne.NewSize(150, height), r.key),
+		container.NewGridWrap(fyne.NewSize(90, height), r.interval),
+	)
+	return widget.NewSimpleRenderer(container.NewBorder(nil, nil, fields, nil, r.summary))
+}
+
+// bind shows entry in the row. Fields are only reset when the row moves to
+// another entry, so the periodic stats refresh leaves text being typed alone.
+func (r *entryRow) bind(entry *core.KeyEntry, stats core.TaskStats, hasStats bool) {
+	if r.entry != entry {
+		r.entry = entry
+		r.key.SetText(entry.Key)
+		r.interval.SetText(strconv.Itoa(entry.IntervalMS))
+		switch {
+		case entry.IsSequence():
+			r.key.SetPlaceHolder("Sequence")
+		case entry.IsText():
+			r.key.SetPlaceHolder("Text")
+		default:
+			r.key.SetPlaceHolder("ex: F5, CTRL+C, CLICK")
+		}
+	}
+	// setEnabled ignores the change notification this triggers.
+	r.enabled.SetChecked(entry.Enabled)
+	r.summary.SetText(rowSummary(entry, stats, hasStats))
+}
+
+func (r *entryRow) setEnabled(checked bool) {
+	if r.entry == nil || r.entry.Enabled == checked {
+		return
+	}
+	r.edit(func(entry *core.KeyEntry) { entry.Enabled = checked })
+}
+
+func (r *entryRow) setKey(text string) {
+	if r.entry == nil {
+		return
+	}
+	key := strings.TrimSpace(text)
+	if !r.entry.IsSequence() && !r.entry.IsText() {
+		if _, err := core.ParseKey(key); err != nil {
+			r.key.SetText(r.entry.Key)
+			r.onInvalid(err.Error())
+			return
+		}
+	}
+	r.edit(func(entry *core.KeyEntry) { entry.Key = key })
+}
+
+func (r *entryRow) setInterval(text string) {
+	if r.entry == nil {
+		return
+	}
+	interval := core.ParseInterval(text)
+	if interval <= 0 {
+		r.interval.SetText(strconv.Itoa(r.entry.IntervalMS))
+		r.onInvalid("Enter a positive interval in ms.")
+		return
+	}
+	r.edit(func(entry *core.KeyEntry) { entry.IntervalMS = interval })
+}
+
+// edit applies change to a copy of the row's entry and passes it to onEdit.
+// The entry itself is left alone, as the control API may be reading it.
+func (r *entryRow) edit(change func(entry *core.KeyEntry)) {
+	old := r.entry
+	r.entry = old.Clone()
+	change(r.entry)
+	r.onEdit(old, r.entry)
+}
+
+// rowSummary is the read-only part of a list row.
+func rowSummary(entry *core.KeyEntry, stats core.TaskStats, hasStats bool) string {
+	var parts []string
+	if entry.IsSequence() {
+		parts = append(parts, fmt.Sprintf("%d steps", len(entry.Steps)))
+	}
+	if entry.IsText() {
+		// An unnamed text entry's key field is empty; show what it types.
+		if strings.TrimSpace(entry.Key) == "" {
+			parts = append(parts, entry.DisplayKey())
+		} else {
+			parts = append(parts, "text")
+		}
+	}
+	if timing := entry.TimingSummary(); timing != "" {
+		parts = append(parts, timing)
+	}
+	if stops := entry.StopSummary(); stops != "" {
+		parts = append(parts, "stops after "+stops)
+	}
+	if hasStats {
+		parts = append(parts, statsSummary(stats))
+	}
+	return strings.Join(parts, " - ")
+}
@@ -4,28 +4,36 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
-	"sync"
-	"syscall"
 	"time"
-	"unicode/utf16"
-	"unsafe"
 
+	"autokeypress/internal/control"
+	"autokeypress/internal/core"
+	"autokeypress/internal/profile"
+	"autokeypress/internal/settings"
 	"github.com/lxn/walk"
 	. "github.com/lxn/walk/declarative"
-	"github.com/micmonay/keybd_event"
 )
 
-type KeyEntry struct {
-	Key        string
-	IntervalMS int
-	Enabled    bool
-}
-
 type KeyTableModel struct {
 	walk.TableModelBase
-	items []*KeyEntry
+	items []*core.KeyEntry
+	stats map[*core.KeyEntry]core.TaskStats
+	// onChange, if set, is called after a row is added (old is nil), edited
+	// in place (old is entry), replaced or removed (entry is nil). The
+	// method that made the change returns its error, leaving the change
+	// made.
+	onChange func(old, entry *core.KeyEntry) error
+}
+
+func (m *KeyTableModel) changed(old, entry *core.KeyEntry) error {
+	if m.onChange != nil {
+		return m.onChange(old, entry)
+	}
+	return nil
 }
 
 func (m *KeyTableModel) RowCount() int {
@@ -36,11 +44,31 @@ func (m *KeyTableModel) Value(row, col int) interface{} {
 	entry := m.items[row]
 	switch col {
 	case 0:
-		return entry.Key
+		return entry.DisplayKey()
 	case 1:
 		return entry.IntervalMS
 	case 2:
 		return entry.Enabled
+	case 3:
+		return entry.TimingSummary()
+	case 4:
+		return entry.StopSummary()
+	case 5:
+		if stats, ok := m.stats[entry]; ok {
+			return stats.Presses
+		}
+		return ""
+	case 6:
+		return formatLastPress(m.stats[entry])
+	case 7:
+		if stats, ok := m.stats[entry]; ok {
+			return stats.Failures
+		}
+		return ""
+	case 8:
+		return formatMeasured(m.stats[entry])
+	case 9:
+		return formatLastError(m.stats[entry])
 	default:
 		return ""
 	}
@@ -52,7 +80,7 @@ func (m *KeyTableModel) SetValue(row, col int, value interface{}) error {
 	case 0:
 		entry.Key = strings.TrimSpace(fmt.Sprintf("%v", value))
 	case 1:
-		entry.IntervalMS = parseInterval(value)
+		entry.IntervalMS = core.ParseInterval(value)
 	case 2:
 		switch v := value.(type) {
 		case bool:
@@ -65,134 +93,376 @@ func (m *KeyTableModel) SetValue(row, col int, value interface{}) error {
 	}
 
 	m.PublishRowChanged(row)
-	return nil
+	return m.changed(entry, entry)
 }
 
-func (m *KeyTableModel) Add(entry *KeyEntry) {
+func (m *KeyTableModel) Add(entry *core.KeyEntry) error {
 	m.items = append(m.items, entry)
 	m.PublishRowsInserted(len(m.items)-1, len(m.items)-1)
+	return m.changed(nil, entry)
 }
 
 func (m *KeyTableModel) Remove(index int) {
 	if index < 0 || index >= len(m.items) {
 		return
 	}
+	removed := m.items[index]
 	m.items = append(m.items[:index], m.items[index+1:]...)
 	m.PublishRowsRemoved(index, index)
+	m.changed(removed, nil)
+}
+
+func (m *KeyTableModel) Replace(index int, entry *core.KeyEntry) error {
+	if index < 0 || index >= len(m.items) {
+		return nil
+	}
+	old := m.items[index]
+	m.items[index] = entry
+	m.PublishRowChanged(index)
+	return m.changed(old, entry)
+}
+
+// SetStats updates the statistics columns.
+func (m *KeyTableModel) SetStats(stats map[*core.KeyEntry]core.TaskStats) {
+	m.stats = stats
+	if len(m.items) > 0 {
+		m.PublishRowsChanged(0, len(m.items)-1)
+	}
+}
+
+func (m *KeyTableModel) SetItems(items []*core.KeyEntry) {
+	m.items = items
+	m.PublishRowsReset()
 }
 
-func (m *KeyTableModel) EnabledEntries() []*KeyEntry {
-	var entries []*KeyEntry
+func (m *KeyTableModel) EnabledEntries() []*core.KeyEntry {
+	var entries []*core.KeyEntry
 	for _, entry := range m.items {
-		if entry.Enabled && entry.IntervalMS > 0 && strings.TrimSpace(entry.Key) != "" {
+		if entry.Active() {
 			entries = append(entries, entry)
 		}
 	}
 	return entries
 }
 
-type KeyTask struct {
-	KeyCode     int
-	UnicodeRune rune
-	UseUnicode  bool
-	Interval    time.Duration
-}
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "run":
+			os.Exit(runCommand(os.Args[2:]))
+		case "ctl":
+			os.Exit(ctlCommand(os.Args[2:]))
+		case "record":
+			os.Exit(recordCommand(os.Args[2:]))
+		}
+	}
 
-type Runner struct {
-	mu      sync.Mutex
-	stopCh  chan struct{}
-	wg      sync.WaitGroup
-	running bool
-}
+	var (
+		mainWindow  *walk.MainWindow
+		tableView   *walk.TableView
+		statusLabel *walk.Label
+		startButton *walk.PushButton
+		stopButton  *walk.PushButton
+		pauseButton *walk.PushButton
 
-func (r *Runner) Start(tasks []KeyTask) {
-	r.mu.Lock()
-	if r.running {
-		r.mu.Unlock()
-		return
-	}
-	r.running = true
-	r.stopCh = make(chan struct{})
-	r.mu.Unlock()
+		recordButton *walk.PushButton
+		recorder     keyRecorder
+	)
 
-	for _, task := range tasks {
-		r.wg.Add(1)
-		go r.runTask(task)
+	model := &KeyTableModel{
+		items: []*core.KeyEntry{
+			{Key: "A", IntervalMS: 1000, Enabled: true},
+		},
+	}
+	current := &profile.Profile{}
+	if last, err := profile.LoadLast(); err == nil {
+		model.items = last.Entries
+		current = last
 	}
-}
 
-func (r *Runner) runTask(task KeyTask) {
-	defer r.wg.Done()
+	injector := windowsInjector{}
+	runner := core.NewRunner(injector)
+	appSettings, settingsErr := settings.Load()
+
+	// startKeys starts the enabled entries; the Start button and the control
+	// API each report what it returns in their own way.
+	startKeys := func() ([]string, error) {
+		tasks, skipped := core.BuildTasks(model.EnabledEntries(), injector)
+		if len(tasks) == 0 {
+			return skipped, errNoKeys
+		}
 
-	var kb keybd_event.KeyBonding
-	if !task.UseUnicode {
-		var err error
-		kb, err = keybd_event.NewKeyBonding()
+		delay := time.Duration(appSettings.StartDelaySeconds) * time.Second
+		runner.StartIn(tasks, delay, func(seconds int) {
+			mainWindow.Synchronize(func() {
+				if !runner.IsRunning() {
+					return
+				}
+				if seconds > 0 {
+					statusLabel.SetText(fmt.Sprintf("Status: starting in %ds", seconds))
+				} else {
+					statusLabel.SetText("Status: running")
+				}
+			})
+		})
+		setRunningState(true, startButton, stopButton, pauseButton, statusLabel)
+		return skipped, nil
+	}
+	start := func() {
+		if runner.IsRunning() {
+			return
+		}
+		skipped, err := startKeys()
 		if err != nil {
+			message := "Add at least one enabled key with a positive interval."
+			if len(skipped) > 0 {
+				message = strings.Join(skipped, "\n")
+			}
+			_ = walk.MsgBox(mainWindow, "Start", message, walk.MsgBoxIconWarning)
 			return
 		}
-		kb.SetKeys(task.KeyCode)
+		if len(skipped) > 0 {
+			_ = walk.MsgBox(mainWindow, "Some keys were skipped", strings.Join(skipped, "\n"), walk.MsgBoxIconWarning)
+		}
 	}
-
-	ticker := time.NewTicker(task.Interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-r.stopCh:
+	stop := func() {
+		runner.Stop()
+		setRunningState(false, startButton, stopButton, pauseButton, statusLabel)
+	}
+	// Edits reach the running tasks at once; the other keys keep going.
+	model.onChange = func(old, entry *core.KeyEntry) error {
+		if entry == nil {
+			runner.RemoveEntry(old)
+			return nil
+		}
+		return runner.UpdateEntry(old, entry)
+	}
+	// showNotStarted tells why a key added or edited in the window could
+	// not start.
+	showNotStarted := func(err error) {
+		if err != nil {
+			_ = walk.MsgBox(mainWindow, "Key not started", err.Error(), walk.MsgBoxIconWarning)
+		}
+	}
+	pause := func() {
+		runner.Pause()
+		pauseButton.SetText("Resume")
+		statusLabel.SetText("Status: paused")
+	}
+	resume := func() {
+		runner.Resume()
+		pauseButton.SetText("Pause")
+		statusLabel.SetText("Status: running")
+	}
+	togglePause := func() {
+		if !runner.IsRunning() {
 			return
-		case <-ticker.C:
-			if task.UseUnicode {
-				sendUnicode(task.UnicodeRune)
-			} else {
-				_ = kb.Launching()
+		}
+		if runner.IsPaused() {
+			resume()
+		} else {
+			pause()
+		}
+	}
+	// toggleRecording starts recording, or stops it and opens what was
+	// recorded as a new sequence to review.
+	toggleRecording := func() {
+		if recorder == nil {
+			started, err := startKeyRecorder()
+			if err != nil {
+				_ = walk.MsgBox(mainWindow, "Record", err.Error(), walk.MsgBoxIconError)
+				return
 			}
+			recorder = started
+			recordButton.SetText("Stop Recording")
+			return
+		}
+		steps := core.RecordedSteps(recorder.Stop())
+		recorder = nil
+		recordButton.SetText("Record")
+		if len(steps) == 0 {
+			_ = walk.MsgBox(mainWindow, "Record", "No keys were recorded.", walk.MsgBoxIconInformation)
+			return
+		}
+		entry, ok := showSequenceDialog(mainWindow, "Recorded Macro", macroEntry(steps, 0))
+		if !ok {
+			return
 		}
+		showNotStarted(model.Add(entry))
 	}
-}
+	runner.OnFinished(func() {
+		mainWindow.Synchronize(func() {
+			setRunningState(false, startButton, stopButton, pauseButton, statusLabel)
+		})
+	})
 
-func (r *Runner) Stop() {
-	r.mu.Lock()
-	if !r.running {
-		r.mu.Unlock()
-		return
+	// onUI runs fn on the window's thread and waits for it to finish.
+	onUI := func(fn func()) {
+		done := make(chan struct{})
+		mainWindow.Synchronize(func() {
+			defer close(done)
+			fn()
+		})
+		<-done
 	}
-	close(r.stopCh)
-	r.running = false
-	r.mu.Unlock()
 
-	r.wg.Wait()
-}
-
-func (r *Runner) IsRunning() bool {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	return r.running
-}
+	// The control API calls in from its own goroutines and edits the table
+	// the same way the buttons do, but reports problems to the caller
+	// instead of in message boxes.
+	var api controlAPI
+	ctl := controlFuncs{
+		entries: func() (entries []control.Listed) {
+			onUI(func() { entries = control.Snapshot(model.items) })
+			return entries
+		},
+		add: func(entry *core.KeyEntry) (err error) {
+			if err := checkSupported(entry, injector); err != nil {
+				return err
+			}
+			onUI(func() { err = model.Add(entry) })
+			return err
+		},
+		update: func(index int, entry *core.KeyEntry) (err error) {
+			if err := checkSupported(entry, injector); err != nil {
+				return err
+			}
+			onUI(func() {
+				if index < 0 || index >= len(model.items) {
+					err = control.ErrNotFound
+					return
+				}
+				err = model.Replace(index, entry)
+			})
+			return err
+		},
+		remove: func(index int) (err error) {
+			onUI(func() {
+				if index < 0 || index >= len(model.items) {
+					err = control.ErrNotFound
+					return
+				}
+				model.Remove(index)
+			})
+			return err
+		},
+		start: func() (skipped []string, err error) {
+			onUI(func() { skipped, err = startKeys() })
+			return skipped, err
+		},
+		stop:   func() { onUI(stop) },
+		pause:  func() { onUI(pause) },
+		resume: func() { onUI(resume) },
+	}
 
-func main() {
-	var (
-		mainWindow   *walk.MainWindow
-		tableView    *walk.TableView
-		statusLabel  *walk.Label
-		addButton    *walk.PushButton
-		removeButton *walk.PushButton
-		startButton  *walk.PushButton
-		stopButton   *walk.PushButton
-	)
+	// Hotkeys fire on their own goroutine; the UI is only touched from the
+	// window's thread.
+	var hotkeys *globalHotkeys
+	applySettings := func(s settings.Settings) {
+		appSettings = s
+		runner.StopAfterFailures(s.StopAfterFailures)
+		if err := api.apply(s.ControlAPI, ctl, runner); err != nil {
+			_ = walk.MsgBox(mainWindow, "Control API", err.Error(), walk.MsgBoxIconWarning)
+		}
+		if hotkeys != nil {
+			hotkeys.Close()
+		}
+		var errors []string
+		hotkeys, errors = registerHotkeys(s,
+			func() {
+				mainWindow.Synchronize(func() {
+					if runner.IsRunning() {
+						stop()
+					} else {
+						start()
+					}
+				})
+			},
+			func() {
+				mainWindow.Synchronize(stop)
+			},
+		)
+		if len(errors) > 0 {
+			_ = walk.MsgBox(mainWindow, "Hotkeys", strings.Join(errors, "\n"), walk.MsgBoxIconWarning)
+		}
+	}
 
-	model := &KeyTableModel{
-		items: []*KeyEntry{
-			{Key: "A", IntervalMS: 1000, Enabled: true},
-		},
+	saveProfile := func(saveAs bool) {
+		current.Entries = model.items
+		if saveAs || current.Path == "" {
+			path, ok := showProfileDialog(mainWindow, "Save profile", true)
+			if !ok {
+				return
+			}
+			current = &profile.Profile{Path: path, Entries: model.items}
+		}
+		if err := profile.Save(current); err != nil {
+			_ = walk.MsgBox(mainWindow, "Save", err.Error(), walk.MsgBoxIconError)
+			return
+		}
+		_ = profile.RememberLast(current.Path)
+		mainWindow.SetTitle(windowTitle(current))
 	}
-	runner := &Runner{}
 
-	MainWindow{
+	if err := (MainWindow{
 		AssignTo: &mainWindow,
-		Title:    "Auto Key Presser",
-		MinSize:  Size{Width: 520, Height: 360},
+		Title:    windowTitle(current),
+		MinSize:  Size{Width: 1300, Height: 360},
 		Layout:   VBox{},
+		MenuItems: []MenuItem{
+			Menu{
+				Text: "&File",
+				Items: []MenuItem{
+					Action{
+						Text: "&Load...",
+						OnTriggered: func() {
+							if runner.IsRunning() {
+								_ = walk.MsgBox(mainWindow, "Load", "Stop the keys before loading a profile.", walk.MsgBoxIconInformation)
+								return
+							}
+							path, ok := showProfileDialog(mainWindow, "Load profile", false)
+							if !ok {
+								return
+							}
+							loaded, err := profile.Load(path)
+							if err != nil {
+								_ = walk.MsgBox(mainWindow, "Load", err.Error(), walk.MsgBoxIconError)
+								return
+							}
+							current = loaded
+							model.SetItems(loaded.Entries)
+							_ = profile.RememberLast(loaded.Path)
+							mainWindow.SetTitle(windowTitle(current))
+						},
+					},
+					Action{
+						Text:        "&Save",
+						OnTriggered: func() { saveProfile(false) },
+					},
+					Action{
+						Text:        "Save &As...",
+						OnTriggered: func() { saveProfile(true) },
+					},
+				},
+			},
+			Menu{
+				Text: "&Options",
+				Items: []MenuItem{
+					Action{
+						Text: "&Settings...",
+						OnTriggered: func() {
+							updated, ok := showSettingsDialog(mainWindow, appSettings)
+							if !ok {
+								return
+							}
+							if err := settings.Save(updated); err != nil {
+								_ = walk.MsgBox(mainWindow, "Settings", err.Error(), walk.MsgBoxIconError)
+							}
+							applySettings(updated)
+						},
+					},
+				},
+			},
+		},
 		Children: []Widget{
 			TableView{
 				AssignTo: &tableView,
@@ -201,86 +471,104 @@ func main() {
 					{Title: "Key", Width: 120},
 					{Title: "Interval (ms)", Width: 120},
 					{Title: "Enabled", Width: 80, CheckBoxes: true},
+					{Title: "Timing", Width: 160},
+					{Title: "Stops after", Width: 140},
+					{Title: "Presses", Width: 70},
+					{Title: "Last press", Width: 80},
+					{Title: "Failures", Width: 70},
+					{Title: "Actual interval", Width: 120},
+					{Title: "Last error", Width: 220},
 				},
 			},
 			Composite{
 				Layout: HBox{},
 				Children: []Widget{
 					PushButton{
-						AssignTo: &addButton,
-						Text:     "Add",
+						Text: "Add",
 						OnClicked: func() {
 							entry, ok := showAddDialog(mainWindow)
 							if !ok {
 								return
 							}
-							model.Add(entry)
+							showNotStarted(model.Add(entry))
 						},
 					},
 					PushButton{
-						AssignTo: &removeButton,
-						Text:     "Remove",
+						Text: "Add Sequence",
 						OnClicked: func() {
-							index := tableView.CurrentIndex()
-							if index < 0 {
-								_ = walk.MsgBox(mainWindow, "Remove", "Select a row to remove.", walk.MsgBoxIconInformation)
+							entry, ok := showSequenceDialog(mainWindow, "Add Sequence", &core.KeyEntry{IntervalMS: 1000, Enabled: true})
+							if !ok {
 								return
 							}
-							model.Remove(index)
+							showNotStarted(model.Add(entry))
 						},
 					},
 					PushButton{
-						AssignTo: &startButton,
-						Text:     "Start",
+						Text: "Add Text",
 						OnClicked: func() {
-							if runner.IsRunning() {
+							entry, ok := showTextDialog(mainWindow, "Add Text", &core.KeyEntry{IntervalMS: 1000, Enabled: true})
+							if !ok {
 								return
 							}
-
-							entries := model.EnabledEntries()
-							if len(entries) == 0 {
-								_ = walk.MsgBox(mainWindow, "Start", "Add at least one enabled key with a positive interval.", walk.MsgBoxIconWarning)
+							showNotStarted(model.Add(entry))
+						},
+					},
+					PushButton{
+						AssignTo:  &recordButton,
+						Text:      "Record",
+						OnClicked: toggleRecording,
+					},
+					PushButton{
+						Text: "Edit",
+						OnClicked: func() {
+							index := tableView.CurrentIndex()
+							if index < 0 || !(model.items[index].IsSequence() || model.items[index].IsText()) {
+								_ = walk.MsgBox(mainWindow, "Edit", "Select a sequence or text to edit.", walk.MsgBoxIconInformation)
 								return
 							}
-
-							var tasks []KeyTask
-							var errors []string
-							for _, entry := range entries {
-								task, err := parseKeyInput(entry.Key)
-								if err != nil {
-									errors = append(errors, err.Error())
-									continue
-								}
-								tasks = append(tasks, KeyTask{
-									KeyCode:     task.KeyCode,
-									UnicodeRune: task.UnicodeRune,
-									UseUnicode:  task.UseUnicode,
-									Interval:    time.Duration(entry.IntervalMS) * time.Millisecond,
-								})
+							var (
+								entry *core.KeyEntry
+								ok    bool
+							)
+							if model.items[index].IsText() {
+								entry, ok = showTextDialog(mainWindow, "Edit Text", model.items[index])
+							} else {
+								entry, ok = showSequenceDialog(mainWindow, "Edit Sequence", model.items[index])
 							}
-
-							if len(tasks) == 0 {
-								_ = walk.MsgBox(mainWindow, "Start", strings.Join(errors, "\n"), walk.MsgBoxIconWarning)
+							if !ok {
 								return
 							}
-
-							if len(errors) > 0 {
-								_ = walk.MsgBox(mainWindow, "Some keys were skipped", strings.Join(errors, "\n"), walk.MsgBoxIconWarning)
-							}
-
-							runner.Start(tasks)
-							setRunningState(true, addButton, removeButton, startButton, stopButton, statusLabel)
+							showNotStarted(model.Replace(index, entry))
 						},
 					},
 					PushButton{
-						AssignTo: &stopButton,
-						Text:     "Stop",
-						Enabled:  false,
+						Text: "Remove",
 						OnClicked: func() {
-							runner.Stop()
-							setRunningState(false, addButton, removeButton, startButton, stopButton, statusLabel)
+							index := tableView.CurrentIndex()
+							if index < 0 {
+								_ = walk.MsgBox(mainWindow, "Remove", "Select a row to remove.", walk.MsgBoxIconInformation)
+								return
+							}
+							model.Remove(index)
 						},
 					},
+					PushButton{
+						AssignTo:  &startButton,
+						Text:      "Start",
+						OnClicked: start,
+					},
+					PushButton{
+						AssignTo:  &stopButton,
+						Text:      "Stop",
+						Enabled:   false,
+						OnClicked: stop,
+					},
+					PushButton{
+						AssignTo:  &pauseButton,
+						Text:      "Pause",
+						Enabled:   false,
+						OnClicked: togglePause,
+					},
 				},
 			},
 			Label{
@@ -288,14 +576,44 @@ func main() {
 				Text:     "Status: idle",
 			},
 		},
-	}.Run()
+	}).Create(); err != nil {
+		_ = walk.MsgBox(nil, "Auto Key Presser", err.Error(), walk.MsgBoxIconError)
+		return
+	}
+
+	if settingsErr != nil {
+		_ = walk.MsgBox(mainWindow, "Settings", settingsErr.Error(), walk.MsgBoxIconWarning)
+	}
+	refreshStats(func() {
+		stats := runner.Stats()
+		mainWindow.Synchronize(func() {
+			model.SetStats(stats)
+			if status, ok := runningStatus(model.items, stats); ok && runner.IsRunning() && !runner.IsPaused() {
+				statusLabel.SetText(status)
+			}
+		})
+	})
+	applySettings(appSettings)
+	defer hotkeys.Close()
+	defer api.Close()
+	// Stopping releases any key a sequence holds down.
+	defer runner.Stop()
+	defer func() {
+		if recorder != nil {
+			recorder.Stop()
+		}
+	}()
+
+	mainWindow.Run()
 }
 
-func setRunningState(running bool, addButton, removeButton, startButton, stopButton *walk.PushButton, statusLabel *walk.Label) {
-	addButton.SetEnabled(!running)
-	removeButton.SetEnabled(!running)
+// setRunningState toggles Start/Stop/Pause and the status. The list stays
+// editable while keys run.
+func setRunningState(running bool, startButton, stopButton, pauseButton *walk.PushButton, statusLabel *walk.Label) {
 	startButton.SetEnabled(!running)
 	stopButton.SetEnabled(running)
+	pauseButton.SetEnabled(running)
+	pauseButton.SetText("Pause")
 	if running {
 		statusLabel.SetText("Status: running")
 	} else {
@@ -303,40 +621,83 @@ func setRunningState(running bool, addButton, removeButton, startButton, stopBut
 	}
 }
 
-func showAddDialog(owner walk.Form) (*KeyEntry, bool) {
+func windowTitle(p *profile.Profile) string {
+	if p.Name == "" {
+		return "Auto Key Presser"
+	}
+	return "Auto Key Presser - " + p.Name
+}
+
+func showProfileDialog(owner walk.Form, title string, save bool) (string, bool) {
+	dlg := &walk.FileDialog{
+		Title:  title,
+		Filter: "Profiles (*.yaml;*.yml;*.json)|*.yaml;*.yml;*.json",
+	}
+	if dir, err := profile.Dir(); err == nil {
+		dlg.InitialDirPath = dir
+	}
+
 	var (
-		dlg        *walk.Dialog
-		keyEdit    *walk.LineEdit
-		intervalEd *walk.LineEdit
-		enabledCb  *walk.CheckBox
+		ok  bool
+		err error
+	)
+	if save {
+		ok, err = dlg.ShowSave(owner)
+	} else {
+		ok, err = dlg.ShowOpen(owner)
+	}
+	if err != nil || !ok {
+		return "", false
+	}
+
+	path := dlg.FilePath
+	if save && filepath.Ext(path) == "" {
+		path += ".yaml"
+	}
+	return path, true
+}
+
+func showSettingsDialog(owner walk.Form, current settings.Settings) (settings.Settings, bool) {
+	var (
+		dlg          *walk.Dialog
+		toggleEdit   *walk.LineEdit
+		stopEdit     *walk.LineEdit
+		delayEdit    *walk.LineEdit
+		failuresEdit *walk.LineEdit
+		controlCb    *walk.CheckBox
 	)
 
 	accepted := false
 
 	Dialog{
 		AssignTo: &dlg,
-		Title:    "Add Key",
+		Title:    "Settings",
 		Layout:   VBox{},
-		MinSize:  Size{Width: 300, Height: 160},
+		MinSize:  Size{Width: 300, Height: 300},
 		Children: []Widget{
-			Label{Text: "Key (ex: A, F5, SPACE):"},
-			LineEdit{AssignTo: &keyEdit},
-			Label{Text: "Interval (ms):"},
-			LineEdit{AssignTo: &intervalEd, Text: "1000"},
-			CheckBox{AssignTo: &enabledCb, Text: "Enabled", Checked: true},
+			Label{Text: "Start/stop hotkey (ex: CTRL+SHIFT+F12, empty to disable):"},
+			LineEdit{AssignTo: &toggleEdit, Text: current.ToggleHotkey},
+			Label{Text: "Emergency stop hotkey:"},
+			LineEdit{AssignTo: &stopEdit, Text: current.StopHotkey},
+			Label{Text: "Countdown before the first press (seconds):"},
+			LineEdit{AssignTo: &delayEdit, Text: strconv.Itoa(current.StartDelaySeconds)},
+			Label{Text: "Stop a key after this many failed presses in a row (0: never):"},
+			LineEdit{AssignTo: &failuresEdit, Text: strconv.Itoa(current.StopAfterFailures)},
+			CheckBox{AssignTo: &controlCb, Text: "Serve the local control API", Checked: current.ControlAPI},
 			Composite{
 				Layout: HBox{},
 				Children: []Widget{
 					PushButton{
-						Text: "Add",
+						Text: "OK",
 						OnClicked: func() {
-							key := strings.TrimSpace(keyEdit.Text())
-							interval := parseInterval(intervalEd.Text())
-							if key == "" || interval <= 0 {
-								_ = walk.MsgBox(dlg, "Validation", "Enter a key and a positive interval in ms.", walk.MsgBoxIconWarning)
+							if delay, err := strconv.Atoi(strings.TrimSpace(delayEdit.Text())); err != nil || delay < 0 {
+								_ = walk.MsgBox(dlg, "Validation", "Enter the countdown in whole seconds (0 for none).", walk.MsgBoxIconWarning)
+								return
+							}
+							if failures, err := strconv.Atoi(strings.TrimSpace(failuresEdit.Text())); err != nil || failures < 0 {
+								_ = walk.MsgBox(dlg, "Validation", "Enter the number of failed presses (0 to never stop).", walk.MsgBoxIconWarning)
 								return
 							}
-
 							accepted = true
 							dlg.Accept()
 						},
@@ -353,222 +714,341 @@ func showAddDialog(owner walk.Form) (*KeyEntry, bool) {
 	}.Run(owner)
 
 	if !accepted {
-		return nil, false
+		return current, false
 	}
 
-	return &KeyEntry{
-		Key:        strings.TrimSpace(keyEdit.Text()),
-		IntervalMS: parseInterval(intervalEd.Text()),
-		Enabled:    enabledCb.Checked(),
+	return settings.Settings{
+		ToggleHotkey:      strings.TrimSpace(toggleEdit.Text()),
+		StopHotkey:        strings.TrimSpace(stopEdit.Text()),
+		StartDelaySeconds: core.ParseInterval(delayEdit.Text()),
+		StopAfterFailures: core.ParseInterval(failuresEdit.Text()),
+		ControlAPI:        controlCb.Checked(),
 	}, true
 }
 
-func parseInterval(value interface{}) int {
-	switch v := value.(type) {
-	case int:
-		return v
-	case int32:
-		return int(v)
-	case int64:
-		return int(v)
-	case float64:
-		return int(v)
-	case string:
-		value := strings.TrimSpace(v)
-		if value == "" {
-			return 0
-		}
-		parsed, err := strconv.Atoi(value)
-		if err != nil {
-			return 0
-		}
-		return parsed
-	default:
-		return 0
-	}
-}
+// showSequenceDialog edits a copy of entry, so cancelling leaves it as it was.
+func showSequenceDialog(owner walk.Form, title string, entry *core.KeyEntry) (*core.KeyEntry, bool) {
+	var (
+		dlg        *walk.Dialog
+		nameEdit   *walk.LineEdit
+		intervalEd *walk.LineEdit
+		offsetEdit *walk.LineEdit
+		stepsEdit  *walk.TextEdit
+		quantizeEd *walk.LineEdit
+		enabledCb  *walk.CheckBox
+		stops      stopEdits
+		edited     *core.KeyEntry
+	)
 
-func parseKeyInput(input string) (KeyTask, error) {
-	key := strings.ToUpper(strings.TrimSpace(input))
-	if key == "" {
-		return KeyTask{}, fmt.Errorf("empty key")
-	}
-
-	runes := []rune(strings.TrimSpace(input))
-	if len(runes) == 1 {
-		return KeyTask{
-			UnicodeRune: runes[0],
-			UseUnicode:  true,
-		}, nil
-	}
-
-	if len(key) == 1 {
-		switch key[0] {
-		case 'A':
-			return KeyTask{KeyCode: keybd_event.VK_A}, nil
-		case 'B':
-			return KeyTask{KeyCode: keybd_event.VK_B}, nil
-		case 'C':
-			return KeyTask{KeyCode: keybd_event.VK_C}, nil
-		case 'D':
-			return KeyTask{KeyCode: keybd_event.VK_D}, nil
-		case 'E':
-			return KeyTask{KeyCode: keybd_event.VK_E}, nil
-		case 'F':
-			return KeyTask{KeyCode: keybd_event.VK_F}, nil
-		case 'G':
-			return KeyTask{KeyCode: keybd_event.VK_G}, nil
-		case 'H':
-			return KeyTask{KeyCode: keybd_event.VK_H}, nil
-		case 'I':
-			return KeyTask{KeyCode: keybd_event.VK_I}, nil
-		case 'J':
-			return KeyTask{KeyCode: keybd_event.VK_J}, nil
-		case 'K':
-			return KeyTask{KeyCode: keybd_event.VK_K}, nil
-		case 'L':
-			return KeyTask{KeyCode: keybd_event.VK_L}, nil
-		case 'M':
-			return KeyTask{KeyCode: keybd_event.VK_M}, nil
-		case 'N':
-			return KeyTask{KeyCode: keybd_event.VK_N}, nil
-		case 'O':
-			return KeyTask{KeyCode: keybd_event.VK_O}, nil
-		case 'P':
-			return KeyTask{KeyCode: keybd_event.VK_P}, nil
-		case 'Q':
-			return KeyTask{KeyCode: keybd_event.VK_Q}, nil
-		case 'R':
-			return KeyTask{KeyCode: keybd_event.VK_R}, nil
-		case 'S':
-			return KeyTask{KeyCode: keybd_event.VK_S}, nil
-		case 'T':
-			return KeyTask{KeyCode: keybd_event.VK_T}, nil
-		case 'U':
-			return KeyTask{KeyCode: keybd_event.VK_U}, nil
-		case 'V':
-			return KeyTask{KeyCode: keybd_event.VK_V}, nil
-		case 'W':
-			return KeyTask{KeyCode: keybd_event.VK_W}, nil
-		case 'X':
-			return KeyTask{KeyCode: keybd_event.VK_X}, nil
-		case 'Y':
-			return KeyTask{KeyCode: keybd_event.VK_Y}, nil
-		case 'Z':
-			return KeyTask{KeyCode: keybd_event.VK_Z}, nil
-		case '0':
-			return KeyTask{KeyCode: keybd_event.VK_0}, nil
-		case '1':
-			return KeyTask{KeyCode: keybd_event.VK_1}, nil
-		case '2':
-			return KeyTask{KeyCode: keybd_event.VK_2}, nil
-		case '3':
-			return KeyTask{KeyCode: keybd_event.VK_3}, nil
-		case '4':
-			return KeyTask{KeyCode: keybd_event.VK_4}, nil
-		case '5':
-			return KeyTask{KeyCode: keybd_event.VK_5}, nil
-		case '6':
-			return KeyTask{KeyCode: keybd_event.VK_6}, nil
-		case '7':
-			return KeyTask{KeyCode: keybd_event.VK_7}, nil
-		case '8':
-			return KeyTask{KeyCode: keybd_event.VK_8}, nil
-		case '9':
-			return KeyTask{KeyCode: keybd_event.VK_9}, nil
-		}
-	}
+	Dialog{
+		AssignTo: &dlg,
+		Title:    title,
+		Layout:   VBox{},
+		MinSize:  Size{Width: 340, Height: 560},
+		Children: []Widget{
+			Label{Text: "Name:"},
+			LineEdit{AssignTo: &nameEdit, Text: entry.Key},
+			Label{Text: "Repeat every (ms):"},
+			LineEdit{AssignTo: &intervalEd, Text: fmt.Sprint(entry.IntervalMS)},
+			Label{Text: "Offset (ms, staggers entries with the same interval):"},
+			LineEdit{AssignTo: &offsetEdit, Text: offsetText(entry.OffsetMS)},
+			Label{Text: "Steps, one per line (ex: CTRL+A, wait 50, type hello, hold W 2s):"},
+			TextEdit{AssignTo: &stepsEdit, Text: strings.ReplaceAll(core.FormatSteps(entry.Steps), "\n", "\r\n"), VScroll: true, MinSize: Size{Height: 160}},
+			Label{Text: "Round waits to (ms, merges quickly typed text; empty: as is):"},
+			LineEdit{AssignTo: &quantizeEd},
+			stops.composite(entry),
+			CheckBox{AssignTo: &enabledCb, Text: "Enabled", Checked: entry.Enabled},
+			Composite{
+				Layout: HBox{},
+				Children: []Widget{
+					PushButton{
+						Text: "OK",
+						OnClicked: func() {
+							interval := core.ParseInterval(intervalEd.Text())
+							if interval <= 0 {
+								_ = walk.MsgBox(dlg, "Validation", "Enter a positive interval in ms.", walk.MsgBoxIconWarning)
+								return
+							}
+							offset, err := core.ParseDurationMS(offsetEdit.Text())
+							if err != nil {
+								_ = walk.MsgBox(dlg, "Validation", err.Error(), walk.MsgBoxIconWarning)
+								return
+							}
+							steps, err := core.ParseSteps(strings.ReplaceAll(stepsEdit.Text(), "\r", ""))
+							if err != nil {
+								_ = walk.MsgBox(dlg, "Validation", err.Error(), walk.MsgBoxIconWarning)
+								return
+							}
+							quantum, err := core.ParseDurationMS(quantizeEd.Text())
+							if err != nil {
+								_ = walk.MsgBox(dlg, "Validation", err.Error(), walk.MsgBoxIconWarning)
+								return
+							}
 
-	switch key {
-	case "SPACE":
-		return KeyTask{KeyCode: keybd_event.VK_SPACE}, nil
-	case "ENTER":
-		return KeyTask{KeyCode: keybd_event.VK_ENTER}, nil
-	case "ESC", "ESCAPE":
-		return KeyTask{KeyCode: keybd_event.VK_ESC}, nil
-	case "TAB":
-		return KeyTask{KeyCode: keybd_event.VK_TAB}, nil
-	case "UP":
-		return KeyTask{KeyCode: keybd_event.VK_UP}, nil
-	case "DOWN":
-		return KeyTask{KeyCode: keybd_event.VK_DOWN}, nil
-	case "LEFT":
-		return KeyTask{KeyCode: keybd_event.VK_LEFT}, nil
-	case "RIGHT":
-		return KeyTask{KeyCode: keybd_event.VK_RIGHT}, nil
-	case "F1":
-		return KeyTask{KeyCode: keybd_event.VK_F1}, nil
-	case "F2":
-		return KeyTask{KeyCode: keybd_event.VK_F2}, nil
-	case "F3":
-		return KeyTask{KeyCode: keybd_event.VK_F3}, nil
-	case "F4":
-		return KeyTask{KeyCode: keybd_event.VK_F4}, nil
-	case "F5":
-		return KeyTask{KeyCode: keybd_event.VK_F5}, nil
-	case "F6":
-		return KeyTask{KeyCode: keybd_event.VK_F6}, nil
-	case "F7":
-		return KeyTask{KeyCode: keybd_event.VK_F7}, nil
-	case "F8":
-		return KeyTask{KeyCode: keybd_event.VK_F8}, nil
-	case "F9":
-		return KeyTask{KeyCode: keybd_event.VK_F9}, nil
-	case "F10":
-		return KeyTask{KeyCode: keybd_event.VK_F10}, nil
-	case "F11":
-		return KeyTask{KeyCode: keybd_event.VK_F11}, nil
-	case "F12":
-		return KeyTask{KeyCode: keybd_event.VK_F12}, nil
-	default:
-		return KeyTask{}, fmt.Errorf("unsupported key: %s", input)
-	}
+							copied := *entry
+							if err := stops.apply(&copied); err != nil {
+								_ = walk.MsgBox(dlg, "Validation", err.Error(), walk.MsgBoxIconWarning)
+								return
+							}
+							copied.Key = strings.TrimSpace(nameEdit.Text())
+							copied.IntervalMS = interval
+							copied.OffsetMS = offset
+							copied.Steps = core.QuantizeWaits(steps, quantum)
+							copied.Enabled = enabledCb.Checked()
+							edited = &copied
+							dlg.Accept()
+						},
+					},
+					PushButton{
+						Text: "Cancel",
+						OnClicked: func() {
+							dlg.Cancel()
+						},
+					},
+				},
+			},
+		},
+	}.Run(owner)
+
+	return edited, edited != nil
 }
 
-const (
-	inputKeyboard    = 1
-	keyeventfUnicode = 0x0004
-	keyeventfKeyUp   = 0x0002
-)
+// showTextDialog edits a copy of a text entry, so cancelling leaves it as it
+// was.
+func showTextDialog(owner walk.Form, title string, entry *core.KeyEntry) (*core.KeyEntry, bool) {
+	var (
+		dlg        *walk.Dialog
+		nameEdit   *walk.LineEdit
+		textEdit   *walk.TextEdit
+		fileEdit   *walk.LineEdit
+		intervalEd *walk.LineEdit
+		offsetEdit *walk.LineEdit
+		delayEdit  *walk.LineEdit
+		enabledCb  *walk.CheckBox
+		stops      stopEdits
+		edited     *core.KeyEntry
+	)
 
-type keyboardInput struct {
-	Vk        uint16
-	Scan      uint16
-	Flags     uint32
-	Time      uint32
-	ExtraInfo uintptr
+	Dialog{
+		AssignTo: &dlg,
+		Title:    title,
+		Layout:   VBox{},
+		MinSize:  Size{Width: 340, Height: 540},
+		Children: []Widget{
+			Label{Text: "Name:"},
+			LineEdit{AssignTo: &nameEdit, Text: entry.Key},
+			Label{Text: `Text (ex: Run {{.N}} at {{.Now.Format "15:04"}}\n):`},
+			TextEdit{AssignTo: &textEdit, Text: strings.ReplaceAll(entry.Text, "\n", "\r\n"), VScroll: true, MinSize: Size{Height: 100}},
+			Label{Text: "Or a text file to type:"},
+			LineEdit{AssignTo: &fileEdit, Text: entry.TextFile},
+			Label{Text: "Repeat every (ms):"},
+			LineEdit{AssignTo: &intervalEd, Text: fmt.Sprint(entry.IntervalMS)},
+			Label{Text: "Offset (ms, staggers entries with the same interval):"},
+			LineEdit{AssignTo: &offsetEdit, Text: offsetText(entry.OffsetMS)},
+			Label{Text: "Delay per character (ms, for apps that drop fast input):"},
+			LineEdit{AssignTo: &delayEdit, Text: offsetText(entry.TypeDelayMS)},
+			stops.composite(entry),
+			CheckBox{AssignTo: &enabledCb, Text: "Enabled", Checked: entry.Enabled},
+			Composite{
+				Layout: HBox{},
+				Children: []Widget{
+					PushButton{
+						Text: "OK",
+						OnClicked: func() {
+							interval := core.ParseInterval(intervalEd.Text())
+							if interval <= 0 {
+								_ = walk.MsgBox(dlg, "Validation", "Enter a positive interval in ms.", walk.MsgBoxIconWarning)
+								return
+							}
+							offset, err := core.ParseDurationMS(offsetEdit.Text())
+							if err != nil {
+								_ = walk.MsgBox(dlg, "Validation", err.Error(), walk.MsgBoxIconWarning)
+								return
+							}
+							delay, err := core.ParseDurationMS(delayEdit.Text())
+							if err != nil {
+								_ = walk.MsgBox(dlg, "Validation", err.Error(), walk.MsgBoxIconWarning)
+								return
+							}
+
+							copied := *entry
+							copied.Text = strings.ReplaceAll(textEdit.Text(), "\r", "")
+							copied.TextFile = strings.TrimSpace(fileEdit.Text())
+							if err := core.CheckText(&copied); err != nil {
+								_ = walk.MsgBox(dlg, "Validation", err.Error(), walk.MsgBoxIconWarning)
+								return
+							}
+							if err := stops.apply(&copied); err != nil {
+								_ = walk.MsgBox(dlg, "Validation", err.Error(), walk.MsgBoxIconWarning)
+								return
+							}
+							copied.Key = strings.TrimSpace(nameEdit.Text())
+							copied.IntervalMS = interval
+							copied.OffsetMS = offset
+							copied.TypeDelayMS = delay
+							copied.Enabled = enabledCb.Checked()
+							edited = &copied
+							dlg.Accept()
+						},
+					},
+					PushButton{
+						Text: "Cancel",
+						OnClicked: func() {
+							dlg.Cancel()
+						},
+					},
+				},
+			},
+		},
+	}.Run(owner)
+
+	return edited, edited != nil
 }
 
-type input struct {
-	Type uint32
-	Ki   keyboardInput
-	_    uint64
+func offsetText(ms int) string {
+	if ms <= 0 {
+		return ""
+	}
+	return strconv.Itoa(ms)
 }
 
-var (
-	user32        = syscall.NewLazyDLL("user32.dll")
-	procSendInput = user32.NewProc("SendInput")
-)
+// stopEdits are the stop-condition fields shared by the add and sequence
+// dialogs.
+type stopEdits struct {
+	presses  *walk.LineEdit
+	duration *walk.LineEdit
+	at       *walk.LineEdit
+}
 
-func sendUnicode(r rune) {
-	units := utf16.Encode([]rune{r})
-	for _, unit := range units {
-		sendUnicodeUnit(uint16(unit), 0)
-		sendUnicodeUnit(uint16(unit), keyeventfKeyUp)
+func (s *stopEdits) composite(entry *core.KeyEntry) Composite {
+	presses := ""
+	if entry.MaxPresses > 0 {
+		presses = strconv.Itoa(entry.MaxPresses)
+	}
+	return Composite{
+		Layout: VBox{MarginsZero: true},
+		Children: []Widget{
+			Label{Text: "Stop after N presses (empty: never):"},
+			LineEdit{AssignTo: &s.presses, Text: presses},
+			Label{Text: "Stop after (ex: 90s, 10m):"},
+			LineEdit{AssignTo: &s.duration, Text: core.FormatDurationMS(entry.MaxDurationMS)},
+			Label{Text: "Stop at (HH:MM):"},
+			LineEdit{AssignTo: &s.at, Text: entry.StopAt},
+		},
 	}
 }
 
-func sendUnicodeUnit(scan uint16, flags uint32) {
-	in := input{
-		Type: inputKeyboard,
-		Ki: keyboardInput{
-			Scan:  scan,
-			Flags: keyeventfUnicode | flags,
-		},
+func (s *stopEdits) apply(entry *core.KeyEntry) error {
+	presses, err := core.ParseMaxPresses(s.presses.Text())
+	if err != nil {
+		return err
+	}
+	duration, err := core.ParseDurationMS(s.duration.Text())
+	if err != nil {
+		return err
+	}
+	at, err := core.ParseStopAt(s.at.Text())
+	if err != nil {
+		return err
 	}
-	procSendInput.Call(
-		1,
-		uintptr(unsafe.Pointer(&in)),
-		unsafe.Sizeof(in),
+	entry.MaxPresses = presses
+	entry.MaxDurationMS = duration
+	entry.StopAt = at
+	return nil
+}
+
+func showAddDialog(owner walk.Form) (*core.KeyEntry, bool) {
+	var (
+		dlg        *walk.Dialog
+		keyEdit    *walk.LineEdit
+		intervalEd *walk.LineEdit
+		offsetEdit *walk.LineEdit
+		jitterEdit *walk.LineEdit
+		gaussianCb *walk.CheckBox
+		holdEdit   *walk.LineEdit
+		enabledCb  *walk.CheckBox
+		stops      stopEdits
+		entry      *core.KeyEntry
 	)
+
+	Dialog{
+		AssignTo: &dlg,
+		Title:    "Add Key",
+		Layout:   VBox{},
+		MinSize:  Size{Width: 300, Height: 460},
+		Children: []Widget{
+			Label{Text: "Key (ex: A, F5, CTRL+C, CLICK, MOVE 100 200):"},
+			LineEdit{AssignTo: &keyEdit},
+			Label{Text: "Interval (ms):"},
+			LineEdit{AssignTo: &intervalEd, Text: "1000"},
+			Label{Text: "Offset (ms, staggers entries with the same interval):"},
+			LineEdit{AssignTo: &offsetEdit},
+			Label{Text: "Jitter (± ms or %, ex: 50, 10%):"},
+			LineEdit{AssignTo: &jitterEdit},
+			CheckBox{AssignTo: &gaussianCb, Text: "Gaussian jitter (cluster near the interval)"},
+			Label{Text: "Hold (ms or range, ex: 40, 30-80):"},
+			LineEdit{AssignTo: &holdEdit},
+			stops.composite(&core.KeyEntry{}),
+			CheckBox{AssignTo: &enabledCb, Text: "Enabled", Checked: true},
+			Composite{
+				Layout: HBox{},
+				Children: []Widget{
+					PushButton{
+						Text: "Add",
+						OnClicked: func() {
+							key := strings.TrimSpace(keyEdit.Text())
+							interval := core.ParseInterval(intervalEd.Text())
+							if !core.ValidEntry(key, interval) {
+								_ = walk.MsgBox(dlg, "Validation", "Enter a key and a positive interval in ms.", walk.MsgBoxIconWarning)
+								return
+							}
+							offset, err := core.ParseDurationMS(offsetEdit.Text())
+							if err != nil {
+								_ = walk.MsgBox(dlg, "Validation", err.Error(), walk.MsgBoxIconWarning)
+								return
+							}
+							jitterMS, jitterPercent, err := core.ParseJitter(jitterEdit.Text())
+							if err != nil {
+								_ = walk.MsgBox(dlg, "Validation", err.Error(), walk.MsgBoxIconWarning)
+								return
+							}
+							holdMin, holdMax, err := core.ParseHold(holdEdit.Text())
+							if err != nil {
+								_ = walk.MsgBox(dlg, "Validation", err.Error(), walk.MsgBoxIconWarning)
+								return
+							}
+
+							entry = &core.KeyEntry{
+								Key:           key,
+								IntervalMS:    interval,
+								OffsetMS:      offset,
+								Enabled:       enabledCb.Checked(),
+								JitterMS:      jitterMS,
+								JitterPercent: jitterPercent,
+								HoldMinMS:     holdMin,
+								HoldMaxMS:     holdMax,
+							}
+							if gaussianCb.Checked() {
+								entry.JitterDistribution = core.DistributionGaussian
+							}
+							if err := stops.apply(entry); err != nil {
+								entry = nil
+								_ = walk.MsgBox(dlg, "Validation", err.Error(), walk.MsgBoxIconWarning)
+								return
+							}
+							dlg.Accept()
+						},
+					},
+					PushButton{
+						Text: "Cancel",
+						OnClicked: func() {
+							dlg.Cancel()
+						},
+					},
+				},
+			},
+		},
+	}.Run(owner)
+
+	return entry, entry != nil
 }
@@ -0,0 +1,964 @@
+//go:build darwin || (linux && gui)
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"autokeypress/internal/control"
+	"autokeypress/internal/core"
+	"autokeypress/internal/profile"
+	"autokeypress/internal/settings"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "run":
+			os.Exit(runCommand(os.Args[2:]))
+		case "ctl":
+			os.Exit(ctlCommand(os.Args[2:]))
+		case "record":
+			os.Exit(recordCommand(os.Args[2:]))
+		}
+	}
+
+	entries := []*core.KeyEntry{
+		{Key: "A", IntervalMS: 1000, Enabled: true},
+	}
+	current := &profile.Profile{}
+	if last, err := profile.LoadLast(); err == nil {
+		entries = last.Entries
+		current = last
+	}
+
+	application := app.New()
+	window := application.NewWindow(windowTitle(current))
+	window.Resize(fyne.NewSize(900, 400))
+
+	statusLabel := widget.NewLabel("Status: idle")
+	injector, closeInjector, injectorErr := openUIInjector()
+	if injectorErr == nil {
+		defer closeInjector()
+	}
+	runner := core.NewRunner(injector)
+	appSettings, settingsErr := settings.Load()
+
+	// entriesMu guards entries and selectedIndex, which the control API
+	// reaches from its own goroutines. It is never held across list calls
+	// such as Refresh and Select, which call back into the list's
+	// callbacks. Entries in the list are not changed in place but replaced,
+	// so a copy of the list can be read outside the lock.
+	var entriesMu sync.Mutex
+	selectedIndex := -1
+	// currentEntries is a copy of the list to read outside the lock.
+	currentEntries := func() []*core.KeyEntry {
+		entriesMu.Lock()
+		defer entriesMu.Unlock()
+		return slices.Clone(entries)
+	}
+	var startButton *widget.Button
+	var stopButton *widget.Button
+	var pauseButton *widget.Button
+
+	// Edits reach the running tasks at once; the other keys keep going.
+	applyEdit := func(old, entry *core.KeyEntry) {
+		if err := runner.UpdateEntry(old, entry); err != nil {
+			dialog.ShowInformation("Key not started", err.Error(), window)
+		}
+	}
+
+	var list *widget.List
+	// replaceEntry swaps old for entry wherever old is now, as the list may
+	// have changed since old was read, such as while a dialog was open. It
+	// reports false if old is gone.
+	replaceEntry := func(old, entry *core.KeyEntry) bool {
+		entriesMu.Lock()
+		index := slices.Index(entries, old)
+		if index >= 0 {
+			entries[index] = entry
+		}
+		entriesMu.Unlock()
+		if index < 0 {
+			return false
+		}
+		list.Refresh()
+		return true
+	}
+
+	list = widget.NewList(
+		func() int {
+			entriesMu.Lock()
+			defer entriesMu.Unlock()
+			return len(entries)
+		},
+		func() fyne.CanvasObject {
+			return newEntryRow(
+				func(old, entry *core.KeyEntry) {
+					if replaceEntry(old, entry) {
+						applyEdit(old, entry)
+					}
+				},
+				func(message string) {
+					dialog.ShowInformation("Validation", message, window)
+				},
+			)
+		},
+		func(i int, o fyne.CanvasObject) {
+			entriesMu.Lock()
+			if i >= len(entries) {
+				entriesMu.Unlock()
+				return
+			}
+			entry := entries[i]
+			entriesMu.Unlock()
+			stats, ok := runner.Stats()[entry]
+			o.(*entryRow).bind(entry, stats, ok)
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		entriesMu.Lock()
+		defer entriesMu.Unlock()
+		selectedIndex = id
+	}
+	list.OnUnselected = func(id widget.ListItemID) {
+		entriesMu.Lock()
+		defer entriesMu.Unlock()
+		if selectedIndex == id {
+			selectedIndex = -1
+		}
+	}
+	// selected returns the selected entry and its index, or -1 and nil.
+	selected := func() (int, *core.KeyEntry) {
+		entriesMu.Lock()
+		defer entriesMu.Unlock()
+		if selectedIndex < 0 || selectedIndex >= len(entries) {
+			return -1, nil
+		}
+		return selectedIndex, entries[selectedIndex]
+	}
+
+	addEntry := func(entry *core.KeyEntry) {
+		entriesMu.Lock()
+		entries = append(entries, entry)
+		entriesMu.Unlock()
+		list.Refresh()
+		applyEdit(nil, entry)
+	}
+	addButton := widget.NewButton("Add", func() {
+		showAddDialog(window, addEntry)
+	})
+
+	addSeqButton := widget.NewButton("Add Sequence", func() {
+		showSequenceDialog(window, "Add Sequence", &core.KeyEntry{IntervalMS: 1000, Enabled: true}, addEntry)
+	})
+
+	// Recording runs until the button is clicked again, then opens the
+	// result as a new sequence to review.
+	var (
+		recorder     keyRecorder
+		recordButton *widget.Button
+	)
+	recordButton = widget.NewButton("Record", func() {
+		if recorder == nil {
+			started, err := startKeyRecorder()
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			recorder = started
+			recordButton.SetText("Stop Recording")
+			return
+		}
+		steps := core.RecordedSteps(recorder.Stop())
+		recorder = nil
+		recordButton.SetText("Record")
+		if len(steps) == 0 {
+			dialog.ShowInformation("Record", "No keys were recorded.", window)
+			return
+		}
+		showSequenceDialog(window, "Recorded Macro", macroEntry(steps, 0), addEntry)
+	})
+
+	addTextButton := widget.NewButton("Add Text", func() {
+		showTextDialog(window, "Add Text", &core.KeyEntry{IntervalMS: 1000, Enabled: true}, addEntry)
+	})
+
+	// Keys are edited in the list itself; sequences and text need a dialog.
+	editButton := widget.NewButton("Edit", func() {
+		_, original := selected()
+		if original == nil || !(original.IsSequence() || original.IsText()) {
+			dialog.ShowInformation("Edit", "Select a sequence or text to edit.", window)
+			return
+		}
+		onSave := func(entry *core.KeyEntry) {
+			if replaceEntry(original, entry) {
+				applyEdit(original, entry)
+			}
+		}
+		if original.IsText() {
+			showTextDialog(window, "Edit Text", original, onSave)
+			return
+		}
+		showSequenceDialog(window, "Edit Sequence", original, onSave)
+	})
+
+	duplicateButton := widget.NewButton("Duplicate", func() {
+		entriesMu.Lock()
+		if selectedIndex < 0 || selectedIndex >= len(entries) {
+			entriesMu.Unlock()
+			dialog.ShowInformation("Duplicate", "Select a row to duplicate.", window)
+			return
+		}
+		index := selectedIndex + 1
+		clone := entries[selectedIndex].Clone()
+		entries = append(entries[:index], append([]*core.KeyEntry{clone}, entries[index:]...)...)
+		entriesMu.Unlock()
+		list.Refresh()
+		list.Select(index)
+		applyEdit(nil, clone)
+	})
+
+	// move swaps the selected row with its neighbour delta rows away.
+	move := func(delta int) {
+		entriesMu.Lock()
+		if selectedIndex < 0 || selectedIndex >= len(entries) {
+			entriesMu.Unlock()
+			dialog.ShowInformation("Move", "Select a row to move.", window)
+			return
+		}
+		target := selectedIndex + delta
+		if target < 0 || target >= len(entries) {
+			entriesMu.Unlock()
+			return
+		}
+		entries[selectedIndex], entries[target] = entries[target], entries[selectedIndex]
+		entriesMu.Unlock()
+		list.Refresh()
+		list.Select(target)
+	}
+	upButton := widget.NewButton("Up", func() { move(-1) })
+	downButton := widget.NewButton("Down", func() { move(1) })
+
+	// removeAt reports false when there is no entry at index.
+	removeAt := func(index int) bool {
+		entriesMu.Lock()
+		if index < 0 || index >= len(entries) {
+			entriesMu.Unlock()
+			return false
+		}
+		removed := entries[index]
+		entries = append(entries[:index], entries[index+1:]...)
+		selectedIndex = -1
+		entriesMu.Unlock()
+		list.UnselectAll()
+		list.Refresh()
+		runner.RemoveEntry(removed)
+		return true
+	}
+	removeButton := widget.NewButton("Remove", func() {
+		index, entry := selected()
+		if entry == nil {
+			dialog.ShowInformation("Remove", "Select a row to remove.", window)
+			return
+		}
+		removeAt(index)
+	})
+
+	var tray *trayMenu
+	setRunning := func(running bool) {
+		setRunningStateFyne(running, statusLabel, startButton, stopButton, pauseButton)
+		tray.update(running, current.Path)
+	}
+
+	// startKeys starts the enabled entries; the Start button, the hotkeys and
+	// the control API each report what it returns in their own way. The
+	// hotkeys and the API call it from their own goroutines, so it reads the
+	// list through currentEntries.
+	startKeys := func() ([]string, error) {
+		if injectorErr != nil {
+			return nil, injectorErr
+		}
+		if err := checkInjector(); err != nil {
+			return nil, err
+		}
+
+		tasks, skipped := core.BuildTasks(currentEntries(), injector)
+		if len(tasks) == 0 {
+			return skipped, errNoKeys
+		}
+
+		delay := time.Duration(appSettings.StartDelaySeconds) * time.Second
+		runner.StartIn(tasks, delay, func(seconds int) {
+			if !runner.IsRunning() {
+				return
+			}
+			if seconds > 0 {
+				statusLabel.SetText(fmt.Sprintf("Status: starting in %ds", seconds))
+			} else {
+				statusLabel.SetText("Status: running")
+			}
+		})
+		setRunning(true)
+		return skipped, nil
+	}
+	start := func() {
+		if runner.IsRunning() {
+			return
+		}
+		skipped, err := startKeys()
+		switch {
+		case errors.Is(err, errNoKeys):
+			dialog.ShowInformation("Start", "Add at least one enabled key with a positive interval.", window)
+			if len(skipped) > 0 {
+				dialog.ShowInformation("Key errors", strings.Join(skipped, "\n"), window)
+			}
+		case err != nil:
+			dialog.ShowError(err, window)
+		case len(skipped) > 0:
+			dialog.ShowInformation("Some keys were skipped", strings.Join(skipped, "\n"), window)
+		}
+	}
+	stop := func() {
+		runner.Stop()
+		setRunning(false)
+	}
+	runner.OnFinished(func() {
+		setRunning(false)
+	})
+
+	startButton = widget.NewButton("Start", start)
+	stopButton = widget.NewButton("Stop", stop)
+	stopButton.Disable()
+	pause := func() {
+		runner.Pause()
+		pauseButton.SetText("Resume")
+		statusLabel.SetText("Status: paused")
+	}
+	resume := func() {
+		runner.Resume()
+		pauseButton.SetText("Pause")
+		statusLabel.SetText("Status: running")
+	}
+	pauseButton = widget.NewButton("Pause", func() {
+		if !runner.IsRunning() {
+			return
+		}
+		if runner.IsPaused() {
+			resume()
+		} else {
+			pause()
+		}
+	})
+	pauseButton.Disable()
+
+	// The control API calls in from its own goroutines and edits the list
+	// the same way the buttons do, under entriesMu, but reports problems to
+	// the caller instead of in dialogs.
+	var api controlAPI
+	ctl := controlFuncs{
+		entries: func() []control.Listed {
+			entriesMu.Lock()
+			defer entriesMu.Unlock()
+			return control.Snapshot(entries)
+		},
+		add: func(entry *core.KeyEntry) error {
+			if err := checkSupported(entry, injector); err != nil {
+				return err
+			}
+			entriesMu.Lock()
+			entries = append(entries, entry)
+			entriesMu.Unlock()
+			list.Refresh()
+			return runner.UpdateEntry(nil, entry)
+		},
+		update: func(index int, entry *core.KeyEntry) error {
+			if err := checkSupported(entry, injector); err != nil {
+				return err
+			}
+			entriesMu.Lock()
+			if index < 0 || index >= len(entries) {
+				entriesMu.Unlock()
+				return control.ErrNotFound
+			}
+			old := entries[index]
+			entries[index] = entry
+			entriesMu.Unlock()
+			list.Refresh()
+			return runner.UpdateEntry(old, entry)
+		},
+		remove: func(index int) error {
+			if !removeAt(index) {
+				return control.ErrNotFound
+			}
+			return nil
+		},
+		start:  startKeys,
+		stop:   stop,
+		pause:  pause,
+		resume: resume,
+	}
+
+	var hotkeys *globalHotkeys
+	applySettings := func(s settings.Settings) {
+		appSettings = s
+		runner.StopAfterFailures(s.StopAfterFailures)
+		if err := api.apply(s.ControlAPI, ctl, runner); err != nil {
+			dialog.ShowError(fmt.Errorf("control API: %w", err), window)
+		}
+		if hotkeys != nil {
+			hotkeys.Close()
+		}
+		// The hotkeys fire on their own goroutine; start and stop only reach
+		// the list through currentEntries.
+		var errors []string
+		hotkeys, errors = registerHotkeys(s,
+			func() {
+				if runner.IsRunning() {
+					stop()
+				} else {
+					start()
+				}
+			},
+			stop,
+		)
+		if len(errors) > 0 {
+			dialog.ShowInformation("Hotkeys", strings.Join(errors, "\n"), window)
+		}
+	}
+
+	useProfile := func(p *profile.Profile) {
+		current = p
+		_ = profile.RememberLast(p.Path)
+		window.SetTitle(windowTitle(current))
+		tray.update(runner.IsRunning(), current.Path)
+	}
+	saveProfile := func(p *profile.Profile) {
+		p.Entries = currentEntries()
+		if err := profile.Save(p); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		useProfile(p)
+	}
+	loadProfile := func(path string) {
+		loaded, err := profile.Load(path)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		entriesMu.Lock()
+		entries = loaded.Entries
+		selectedIndex = -1
+		entriesMu.Unlock()
+		list.UnselectAll()
+		list.Refresh()
+		useProfile(loaded)
+	}
+	saveProfileAs := func() {
+		showProfileDialog(window, true, func(path string) {
+			saveProfile(&profile.Profile{Path: path})
+		})
+	}
+
+	window.SetMainMenu(fyne.NewMainMenu(
+		fyne.NewMenu("File",
+			fyne.NewMenuItem("Load...", func() {
+				if runner.IsRunning() {
+					dialog.ShowInformation("Load", "Stop the keys before loading a profile.", window)
+					return
+				}
+				showProfileDialog(window, false, loadProfile)
+			}),
+			fyne.NewMenuItem("Save", func() {
+				if current.Path == "" {
+					saveProfileAs()
+					return
+				}
+				saveProfile(current)
+			}),
+			fyne.NewMenuItem("Save As...", saveProfileAs),
+		),
+		fyne.NewMenu("Options",
+			fyne.NewMenuItem("Settings...", func() {
+				showSettingsDialog(window, appSettings, func(updated settings.Settings) {
+					if err := settings.Save(updated); err != nil {
+						dialog.ShowError(err, window)
+					}
+					applySettings(updated)
+				})
+			}),
+		),
+	))
+
+	// With a tray icon, closing the window only hides it; Quit is in the tray
+	// menu.
+	tray = newTrayMenu(application,
+		func() {
+			window.Show()
+			window.RequestFocus()
+		},
+		start,
+		stop,
+		func(name string) {
+			if runner.IsRunning() {
+				return
+			}
+			loadProfile(name)
+		},
+	)
+	if tray != nil {
+		tray.update(false, current.Path)
+		window.SetCloseIntercept(window.Hide)
+	}
+
+	controls := container.NewHBox(addButton, addSeqButton, addTextButton, recordButton, editButton, duplicateButton, upButton, downButton, removeButton, startButton, stopButton, pauseButton)
+	content := container.NewBorder(controls, statusLabel, nil, nil, list)
+	window.SetContent(content)
+
+	refreshStats(func() {
+		list.Refresh()
+		if status, ok := runningStatus(currentEntries(), runner.Stats()); ok && runner.IsRunning() && !runner.IsPaused() {
+			statusLabel.SetText(status)
+		}
+	})
+
+	// Registering needs the app's event loop, so wait until it is running.
+	application.Lifecycle().SetOnStarted(func() {
+		if injectorErr != nil {
+			dialog.ShowError(injectorErr, window)
+		}
+		if settingsErr != nil {
+			dialog.ShowError(settingsErr, window)
+		}
+		applySettings(appSettings)
+	})
+	application.Lifecycle().SetOnStopped(func() {
+		// Stopping releases any key a sequence holds down.
+		runner.Stop()
+		api.Close()
+		if recorder != nil {
+			recorder.Stop()
+		}
+		if hotkeys != nil {
+			hotkeys.Close()
+		}
+	})
+
+	window.ShowAndRun()
+}
+
+// setRunningStateFyne toggles Start/Stop/Pause and the status. The list stays
+// editable while keys run.
+func setRunningStateFyne(running bool, statusLabel *widget.Label, startButton, stopButton, pauseButton *widget.Button) {
+	pauseButton.SetText("Pause")
+	if running {
+		statusLabel.SetText("Status: running")
+		startButton.Disable()
+		stopButton.Enable()
+		pauseButton.Enable()
+	} else {
+		statusLabel.SetText("Status: idle")
+		startButton.Enable()
+		stopButton.Disable()
+		pauseButton.Disable()
+	}
+}
+
+func windowTitle(p *profile.Profile) string {
+	if p.Name == "" {
+		return "Auto Key Presser"
+	}
+	return "Auto Key Presser - " + p.Name
+}
+
+func showProfileDialog(window fyne.Window, save bool, onPath func(string)) {
+	var fileDialog *dialog.FileDialog
+	if save {
+		fileDialog = dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil || writer == nil {
+				return
+			}
+			path := writer.URI().Path()
+			_ = writer.Close()
+			if filepath.Ext(path) == "" {
+				_ = os.Remove(path)
+				path += ".yaml"
+			}
+			onPath(path)
+		}, window)
+		fileDialog.SetFileName("profile.yaml")
+	} else {
+		fileDialog = dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			path := reader.URI().Path()
+			_ = reader.Close()
+			onPath(path)
+		}, window)
+	}
+
+	fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".yaml", ".yml", ".json"}))
+	if dir, err := profile.Dir(); err == nil {
+		if location, err := storage.ListerForURI(storage.NewFileURI(dir)); err == nil {
+			fileDialog.SetLocation(location)
+		}
+	}
+	fileDialog.Show()
+}
+
+func showSettingsDialog(window fyne.Window, current settings.Settings, onSave func(settings.Settings)) {
+	toggleEntry := widget.NewEntry()
+	toggleEntry.SetText(current.ToggleHotkey)
+	toggleEntry.SetPlaceHolder("disabled")
+	stopEntry := widget.NewEntry()
+	stopEntry.SetText(current.StopHotkey)
+	stopEntry.SetPlaceHolder("disabled")
+
+	delayEntry := widget.NewEntry()
+	delayEntry.SetText(strconv.Itoa(current.StartDelaySeconds))
+	failuresEntry := widget.NewEntry()
+	failuresEntry.SetText(strconv.Itoa(current.StopAfterFailures))
+	controlCheck := widget.NewCheck("Serve the local control API", nil)
+	controlCheck.SetChecked(current.ControlAPI)
+
+	form := dialog.NewForm("Settings", "Save", "Cancel",
+		[]*widget.FormItem{
+			widget.NewFormItem("Start/stop hotkey (ex: CTRL+SHIFT+F12)", toggleEntry),
+			widget.NewFormItem("Emergency stop hotkey", stopEntry),
+			widget.NewFormItem("Countdown before first press (s)", delayEntry),
+			widget.NewFormItem("Stop a key after N failures in a row (0: never)", failuresEntry),
+			widget.NewFormItem("", controlCheck),
+		},
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			delay, err := strconv.Atoi(strings.TrimSpace(delayEntry.Text))
+			if err != nil || delay < 0 {
+				dialog.ShowInformation("Validation", "Enter the countdown in whole seconds (0 for none).", window)
+				return
+			}
+			failures, err := strconv.Atoi(strings.TrimSpace(failuresEntry.Text))
+			if err != nil || failures < 0 {
+				dialog.ShowInformation("Validation", "Enter the number of failed presses (0 to never stop).", window)
+				return
+			}
+			onSave(settings.Settings{
+				ToggleHotkey:      strings.TrimSpace(toggleEntry.Text),
+				StopHotkey:        strings.TrimSpace(stopEntry.Text),
+				StartDelaySeconds: delay,
+				StopAfterFailures: failures,
+				ControlAPI:        controlCheck.Checked,
+			})
+		},
+		window,
+	)
+	form.Show()
+}
+
+// showSequenceDialog edits a copy of entry, so cancelling leaves it as it was.
+func showSequenceDialog(window fyne.Window, title string, entry *core.KeyEntry, onSave func(*core.KeyEntry)) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(entry.Key)
+	nameEntry.SetPlaceHolder("Sequence")
+	intervalEntry := widget.NewEntry()
+	intervalEntry.SetText(fmt.Sprint(entry.IntervalMS))
+	offsetEntry := widget.NewEntry()
+	offsetEntry.SetText(offsetText(entry.OffsetMS))
+	offsetEntry.SetPlaceHolder("0")
+	stepsEntry := widget.NewMultiLineEntry()
+	stepsEntry.SetText(core.FormatSteps(entry.Steps))
+	stepsEntry.SetPlaceHolder("CTRL+A\nwait 50\nCTRL+C\nwait 200\nTAB\ntype hello\nhold W 2s")
+	stepsEntry.SetMinRowsVisible(8)
+	quantizeEntry := widget.NewEntry()
+	quantizeEntry.SetPlaceHolder("0")
+	enabledCheck := widget.NewCheck("Enabled", nil)
+	enabledCheck.SetChecked(entry.Enabled)
+	stops := newStopFields(entry)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Repeat every (ms)", intervalEntry),
+		widget.NewFormItem("Offset (ms)", offsetEntry),
+		widget.NewFormItem("Steps", stepsEntry),
+		widget.NewFormItem("Round waits to (ms)", quantizeEntry),
+	}
+	items = append(items, stops.items()...)
+	items = append(items, widget.NewFormItem("", enabledCheck))
+
+	form := dialog.NewForm(title, "Save", "Cancel", items,
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			interval := core.ParseInterval(intervalEntry.Text)
+			if interval <= 0 {
+				dialog.ShowInformation("Validation", "Enter a positive interval in ms.", window)
+				return
+			}
+			offset, err := core.ParseDurationMS(offsetEntry.Text)
+			if err != nil {
+				dialog.ShowInformation("Validation", err.Error(), window)
+				return
+			}
+			steps, err := core.ParseSteps(stepsEntry.Text)
+			if err != nil {
+				dialog.ShowInformation("Validation", err.Error(), window)
+				return
+			}
+			quantum, err := core.ParseDurationMS(quantizeEntry.Text)
+			if err != nil {
+				dialog.ShowInformation("Validation", err.Error(), window)
+				return
+			}
+
+			edited := *entry
+			if err := stops.apply(&edited); err != nil {
+				dialog.ShowInformation("Validation", err.Error(), window)
+				return
+			}
+			edited.Key = strings.TrimSpace(nameEntry.Text)
+			edited.IntervalMS = interval
+			edited.OffsetMS = offset
+			edited.Steps = core.QuantizeWaits(steps, quantum)
+			edited.Enabled = enabledCheck.Checked
+			onSave(&edited)
+		},
+		window,
+	)
+	form.Resize(fyne.NewSize(420, 600))
+	form.Show()
+}
+
+// showTextDialog edits a copy of a text entry, so cancelling leaves it as it
+// was.
+func showTextDialog(window fyne.Window, title string, entry *core.KeyEntry, onSave func(*core.KeyEntry)) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(entry.Key)
+	nameEntry.SetPlaceHolder("Text")
+	textEntry := widget.NewMultiLineEntry()
+	textEntry.SetText(entry.Text)
+	textEntry.SetPlaceHolder(`Hello\nRun {{.N}} at {{.Now.Format "15:04"}}`)
+	textEntry.SetMinRowsVisible(5)
+	fileEntry := widget.NewEntry()
+	fileEntry.SetText(entry.TextFile)
+	fileEntry.SetPlaceHolder("or a file to type")
+	intervalEntry := widget.NewEntry()
+	intervalEntry.SetText(fmt.Sprint(entry.IntervalMS))
+	offsetEntry := widget.NewEntry()
+	offsetEntry.SetText(offsetText(entry.OffsetMS))
+	offsetEntry.SetPlaceHolder("0")
+	delayEntry := widget.NewEntry()
+	delayEntry.SetText(offsetText(entry.TypeDelayMS))
+	delayEntry.SetPlaceHolder("0")
+	enabledCheck := widget.NewCheck("Enabled", nil)
+	enabledCheck.SetChecked(entry.Enabled)
+	stops := newStopFields(entry)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Text", textEntry),
+		widget.NewFormItem("Text file", fileEntry),
+		widget.NewFormItem("Repeat every (ms)", intervalEntry),
+		widget.NewFormItem("Offset (ms)", offsetEntry),
+		widget.NewFormItem("Delay per character (ms)", delayEntry),
+	}
+	items = append(items, stops.items()...)
+	items = append(items, widget.NewFormItem("", enabledCheck))
+
+	form := dialog.NewForm(title, "Save", "Cancel", items,
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			interval := core.ParseInterval(intervalEntry.Text)
+			if interval <= 0 {
+				dialog.ShowInformation("Validation", "Enter a positive interval in ms.", window)
+				return
+			}
+			offset, err := core.ParseDurationMS(offsetEntry.Text)
+			if err != nil {
+				dialog.ShowInformation("Validation", err.Error(), window)
+				return
+			}
+			delay, err := core.ParseDurationMS(delayEntry.Text)
+			if err != nil {
+				dialog.ShowInformation("Validation", err.Error(), window)
+				return
+			}
+
+			edited := *entry
+			edited.Text = textEntry.Text
+			edited.TextFile = strings.TrimSpace(fileEntry.Text)
+			if err := core.CheckText(&edited); err != nil {
+				dialog.ShowInformation("Validation", err.Error(), window)
+				return
+			}
+			if err := stops.apply(&edited); err != nil {
+				dialog.ShowInformation("Validation", err.Error(), window)
+				return
+			}
+			edited.Key = strings.TrimSpace(nameEntry.Text)
+			edited.IntervalMS = interval
+			edited.OffsetMS = offset
+			edited.TypeDelayMS = delay
+			edited.Enabled = enabledCheck.Checked
+			onSave(&edited)
+		},
+		window,
+	)
+	form.Resize(fyne.NewSize(420, 560))
+	form.Show()
+}
+
+func offsetText(ms int) string {
+	if ms <= 0 {
+		return ""
+	}
+	return strconv.Itoa(ms)
+}
+
+// stopFields are the stop-condition fields shared by the add and sequence
+// dialogs.
+type stopFields struct {
+	presses  *widget.Entry
+	duration *widget.Entry
+	at       *widget.Entry
+}
+
+func newStopFields(entry *core.KeyEntry) *stopFields {
+	f := &stopFields{
+		presses:  widget.NewEntry(),
+		duration: widget.NewEntry(),
+		at:       widget.NewEntry(),
+	}
+	if entry.MaxPresses > 0 {
+		f.presses.SetText(strconv.Itoa(entry.MaxPresses))
+	}
+	f.presses.SetPlaceHolder("never")
+	f.duration.SetText(core.FormatDurationMS(entry.MaxDurationMS))
+	f.duration.SetPlaceHolder("ex: 90s, 10m")
+	f.at.SetText(entry.StopAt)
+	f.at.SetPlaceHolder("HH:MM")
+	return f
+}
+
+func (f *stopFields) items() []*widget.FormItem {
+	return []*widget.FormItem{
+		widget.NewFormItem("Stop after N presses", f.presses),
+		widget.NewFormItem("Stop after", f.duration),
+		widget.NewFormItem("Stop at", f.at),
+	}
+}
+
+func (f *stopFields) apply(entry *core.KeyEntry) error {
+	presses, err := core.ParseMaxPresses(f.presses.Text)
+	if err != nil {
+		return err
+	}
+	duration, err := core.ParseDurationMS(f.duration.Text)
+	if err != nil {
+		return err
+	}
+	at, err := core.ParseStopAt(f.at.Text)
+	if err != nil {
+		return err
+	}
+	entry.MaxPresses = presses
+	entry.MaxDurationMS = duration
+	entry.StopAt = at
+	return nil
+}
+
+func showAddDialog(window fyne.Window, onAdd func(*core.KeyEntry)) {
+	keyEntry := widget.NewEntry()
+	intervalEntry := widget.NewEntry()
+	intervalEntry.SetText("1000")
+	offsetEntry := widget.NewEntry()
+	offsetEntry.SetPlaceHolder("0")
+	jitterEntry := widget.NewEntry()
+	jitterEntry.SetPlaceHolder("none")
+	distributionSelect := widget.NewSelect([]string{core.DistributionUniform, core.DistributionGaussian}, nil)
+	distributionSelect.SetSelected(core.DistributionUniform)
+	holdEntry := widget.NewEntry()
+	holdEntry.SetPlaceHolder("tap")
+	enabledCheck := widget.NewCheck("Enabled", nil)
+	enabledCheck.SetChecked(true)
+	stops := newStopFields(&core.KeyEntry{})
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Key (ex: A, F5, CTRL+C, CLICK, MOVE 100 200)", keyEntry),
+		widget.NewFormItem("Interval (ms)", intervalEntry),
+		widget.NewFormItem("Offset (ms)", offsetEntry),
+		widget.NewFormItem("Jitter (± ms or %, ex: 50, 10%)", jitterEntry),
+		widget.NewFormItem("Jitter distribution", distributionSelect),
+		widget.NewFormItem("Hold (ms or range, ex: 30-80)", holdEntry),
+	}
+	items = append(items, stops.items()...)
+	items = append(items, widget.NewFormItem("", enabledCheck))
+
+	form := dialog.NewForm("Add Key", "Add", "Cancel", items,
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			key := strings.TrimSpace(keyEntry.Text)
+			interval := core.ParseInterval(intervalEntry.Text)
+			if !core.ValidEntry(key, interval) {
+				dialog.ShowInformation("Validation", "Enter a key and a positive interval in ms.", window)
+				return
+			}
+			offset, err := core.ParseDurationMS(offsetEntry.Text)
+			if err != nil {
+				dialog.ShowInformation("Validation", err.Error(), window)
+				return
+			}
+			jitterMS, jitterPercent, err := core.ParseJitter(jitterEntry.Text)
+			if err != nil {
+				dialog.ShowInformation("Validation", err.Error(), window)
+				return
+			}
+			holdMin, holdMax, err := core.ParseHold(holdEntry.Text)
+			if err != nil {
+				dialog.ShowInformation("Validation", err.Error(), window)
+				return
+			}
+			entry := &core.KeyEntry{
+				Key:           key,
+				IntervalMS:    interval,
+				OffsetMS:      offset,
+				Enabled:       enabledCheck.Checked,
+				JitterMS:      jitterMS,
+				JitterPercent: jitterPercent,
+				HoldMinMS:     holdMin,
+				HoldMaxMS:     holdMax,
+			}
+			if distributionSelect.Selected == core.DistributionGaussian {
+				entry.JitterDistribution = core.DistributionGaussian
+			}
+			if err := stops.apply(entry); err != nil {
+				dialog.ShowInformation("Validation", err.Error(), window)
+				return
+			}
+			onAdd(entry)
+		},
+		window,
+	)
+	form.Show()
+}
@@ -0,0 +1,56 @@
+//go:build linux && !gui
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"autokeypress/internal/core"
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "run":
+			os.Exit(runCommand(os.Args[2:]))
+		case "ctl":
+			os.Exit(ctlCommand(os.Args[2:]))
+		case "record":
+			os.Exit(recordCommand(os.Args[2:]))
+		}
+	}
+
+	entries := []*core.KeyEntry{
+		{Key: "A", IntervalMS: 1000, Enabled: true},
+	}
+	if len(os.Args) > 1 {
+		parsed, err := parseArgs(os.Args[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, "usage: autokeypress [KEY INTERVAL_MS]...")
+			fmt.Fprint(os.Stderr, runUsage)
+			os.Exit(exitUsage)
+		}
+		entries = parsed
+	}
+	os.Exit(runHeadless(entries, 0, 0, 0, 0))
+}
+
+func parseArgs(args []string) ([]*core.KeyEntry, error) {
+	if len(args)%2 != 0 {
+		return nil, fmt.Errorf("expected KEY INTERVAL_MS pairs")
+	}
+
+	var entries []*core.KeyEntry
+	for i := 0; i < len(args); i += 2 {
+		key := strings.TrimSpace(args[i])
+		interval := core.ParseInterval(args[i+1])
+		if !core.ValidEntry(key, interval) {
+			return nil, fmt.Errorf("invalid entry %q %q: enter a key and a positive interval in ms", args[i], args[i+1])
+		}
+		entries = append(entries, &core.KeyEntry{Key: key, IntervalMS: interval, Enabled: true})
+	}
+	return entries, nil
+}
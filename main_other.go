@@ -1,9 +1,9 @@
-//go:build !windows && !darwin
+//go:build !windows && !darwin && !linux
 
 package main
 
 import "fmt"
 
 func main() {
-	fmt.Println("This app currently supports Windows and macOS only.")
+	fmt.Println("This app currently supports Windows, macOS and Linux only.")
 }
@@ -0,0 +1,135 @@
+//go:build windows || darwin || linux
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"autokeypress/internal/core"
+	"autokeypress/internal/profile"
+)
+
+// keyRecorder captures the keys pressed in every app until stopped. Keys the
+// app itself sends are not recorded where the platform can tell them apart.
+type keyRecorder interface {
+	// Stop ends the recording and returns the events in time order.
+	Stop() []core.KeyEvent
+}
+
+// startKeyRecorder starts a recording; tests replace it with a fake.
+var startKeyRecorder = startRecording
+
+// macroName is the name recorded macros get in the list.
+const macroName = "Macro"
+
+const recordUsage = `usage: autokeypress record [--quantize MS] [--trim-start N] [--trim-end N]
+                           [--duration D] [--save PROFILE] [--interval MS]
+
+Records the keys pressed in any app until Ctrl+C (or --duration) and prints
+them as sequence steps. --quantize rounds the waits between keys to a
+multiple of MS, merging quickly typed text into one step. --trim-start and
+--trim-end drop that many presses from either end; the Ctrl+C that stops the
+recording is always dropped. --save adds the macro to a profile file or
+saved profile (created if needed), repeating every --interval ms (default:
+the macro's length plus one second), ready for "autokeypress run".
+`
+
+// recordCommand implements the "record" subcommand and returns the process
+// exit code.
+func recordCommand(args []string) int {
+	fs := flag.NewFlagSet("record", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), recordUsage)
+	}
+	quantize := fs.Int("quantize", 0, "round waits to a multiple of this many ms")
+	trimStart := fs.Int("trim-start", 0, "presses to drop from the start")
+	trimEnd := fs.Int("trim-end", 0, "presses to drop from the end")
+	duration := fs.Duration("duration", 0, "stop recording after this long")
+	save := fs.String("save", "", "profile to add the macro to")
+	interval := fs.Int("interval", 0, "repeat the saved macro every this many ms")
+	if err := parseInterspersed(fs, args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 || *quantize < 0 || *trimStart < 0 || *trimEnd < 0 || *interval < 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	recorder, err := startKeyRecorder()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "record:", err)
+		return exitError
+	}
+	fmt.Fprintln(os.Stderr, "Recording, press Ctrl+C to stop")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	var timeout <-chan time.Time
+	if *duration > 0 {
+		timer := time.NewTimer(*duration)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	interrupted := false
+	select {
+	case <-signals:
+		interrupted = true
+	case <-timeout:
+	}
+
+	steps := core.RecordedSteps(recorder.Stop())
+	if interrupted && len(steps) > 0 && steps[len(steps)-1].Key == "CTRL+C" {
+		steps = steps[:len(steps)-1]
+	}
+	steps = core.QuantizeWaits(core.TrimSteps(steps, *trimStart, *trimEnd), *quantize)
+	if len(steps) == 0 {
+		fmt.Fprintln(os.Stderr, "record: no keys were recorded")
+		return exitError
+	}
+	fmt.Println(core.FormatSteps(steps))
+
+	if *save != "" {
+		if err := saveMacro(*save, macroEntry(steps, *interval)); err != nil {
+			fmt.Fprintln(os.Stderr, "record:", err)
+			return exitError
+		}
+	}
+	return exitOK
+}
+
+// macroEntry is a recorded macro as a sequence entry. Without an interval it
+// repeats a second after it finishes.
+func macroEntry(steps []core.Step, intervalMS int) *core.KeyEntry {
+	if intervalMS <= 0 {
+		intervalMS = int((core.StepsDuration(steps) + time.Second).Milliseconds())
+	}
+	return &core.KeyEntry{Key: macroName, IntervalMS: intervalMS, Enabled: true, Steps: steps}
+}
+
+// saveMacro adds entry to the profile at path (a file or saved profile name),
+// creating it when it does not exist yet.
+func saveMacro(path string, entry *core.KeyEntry) error {
+	p, err := profile.Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		p, err = &profile.Profile{Path: path}, nil
+		if filepath.Base(path) == path && filepath.Ext(path) == "" {
+			p = &profile.Profile{Name: path}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	p.Entries = append(p.Entries, entry)
+	return profile.Save(p)
+}
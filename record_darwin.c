@@ -0,0 +1,47 @@
+//go:build darwin
+
+#include <ApplicationServices/ApplicationServices.h>
+#include <unistd.h>
+#include "_cgo_export.h"
+
+// The recording's tap, kept so the callback can turn it back on when macOS
+// disables it.
+static CFMachPortRef akpRecordTapPort;
+
+static CGEventRef akpRecordTap(CGEventTapProxy proxy, CGEventType type, CGEventRef event, void *info) {
+	if (type == kCGEventTapDisabledByTimeout || type == kCGEventTapDisabledByUserInput) {
+		CGEventTapEnable(akpRecordTapPort, true);
+		return event;
+	}
+	// Skip what the app types itself.
+	if (CGEventGetIntegerValueField(event, kCGEventSourceUnixProcessID) == getpid()) {
+		return event;
+	}
+	akpRecordEvent((int)type,
+		(int)CGEventGetIntegerValueField(event, kCGKeyboardEventKeycode),
+		(unsigned long long)CGEventGetFlags(event));
+	return event;
+}
+
+// akpRecordAttach creates a listen-only keyboard tap on the current thread's
+// run loop. It returns 0 when macOS refuses, which means the Input Monitoring
+// permission is missing.
+int akpRecordAttach(void) {
+	CGEventMask mask = CGEventMaskBit(kCGEventKeyDown) | CGEventMaskBit(kCGEventKeyUp) | CGEventMaskBit(kCGEventFlagsChanged);
+	akpRecordTapPort = CGEventTapCreate(kCGSessionEventTap, kCGHeadInsertEventTap,
+		kCGEventTapOptionListenOnly, mask, akpRecordTap, NULL);
+	if (akpRecordTapPort == NULL) {
+		return 0;
+	}
+	CFRunLoopSourceRef source = CFMachPortCreateRunLoopSource(NULL, akpRecordTapPort, 0);
+	CFRunLoopAddSource(CFRunLoopGetCurrent(), source, kCFRunLoopCommonModes);
+	CFRelease(source);
+	CGEventTapEnable(akpRecordTapPort, true);
+	return 1;
+}
+
+void akpRecordDetach(void) {
+	CFMachPortInvalidate(akpRecordTapPort);
+	CFRelease(akpRecordTapPort);
+	akpRecordTapPort = NULL;
+}
@@ -0,0 +1,139 @@
+//go:build darwin
+
+package main
+
+/*
+#include <ApplicationServices/ApplicationServices.h>
+
+int akpRecordAttach(void);
+void akpRecordDetach(void);
+*/
+import "C"
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"autokeypress/internal/core"
+)
+
+// errInputMonitoring is returned when macOS refuses to let the app see keys
+// typed in other apps.
+var errInputMonitoring = errors.New("macOS is blocking key recording: grant Input Monitoring permission to autokeypress (or the terminal running it) in System Settings > Privacy & Security > Input Monitoring")
+
+// macModifierKeys are the modifiers on both sides of the keyboard, which are
+// reported as flag changes rather than key presses.
+var macModifierKeys = map[C.int]core.Key{
+	55: core.KeyMeta, 54: core.KeyMeta,
+	56: core.KeyShift, 60: core.KeyShift,
+	58: core.KeyAlt, 61: core.KeyAlt,
+	59: core.KeyCtrl, 62: core.KeyCtrl,
+}
+
+// macRecordedKeys maps virtual key codes back to keys.
+var macRecordedKeys = func() map[C.int]core.Key {
+	keys := map[C.int]core.Key{}
+	for key, code := range macKeyCodes {
+		keys[C.int(code)] = key
+	}
+	return keys
+}()
+
+// activeMacRecorder receives the events from the tap. The tap is global to
+// the process, so only one recording can run at a time.
+var (
+	activeMacRecorderMu sync.Mutex
+	activeMacRecorder   *macRecorder
+)
+
+// macRecorder records through a listen-only event tap, run on a thread of its
+// own. Keys the app posts itself are skipped.
+type macRecorder struct {
+	start    time.Time
+	stopping atomic.Bool
+	done     chan struct{}
+
+	mu     sync.Mutex
+	events []core.KeyEvent
+}
+
+func startRecording() (keyRecorder, error) {
+	activeMacRecorderMu.Lock()
+	defer activeMacRecorderMu.Unlock()
+	if activeMacRecorder != nil {
+		return nil, errors.New("a recording is already running")
+	}
+	r := &macRecorder{start: time.Now(), done: make(chan struct{})}
+	started := make(chan error, 1)
+	go r.run(started)
+	if err := <-started; err != nil {
+		return nil, err
+	}
+	activeMacRecorder = r
+	return r, nil
+}
+
+func (r *macRecorder) run(started chan<- error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	defer close(r.done)
+
+	if C.akpRecordAttach() == 0 {
+		started <- errInputMonitoring
+		return
+	}
+	defer C.akpRecordDetach()
+	started <- nil
+	// Run the loop in short slices rather than stopping it from Stop, which
+	// could come before the loop starts.
+	for !r.stopping.Load() {
+		C.CFRunLoopRunInMode(C.kCFRunLoopDefaultMode, 0.1, 0)
+	}
+}
+
+func (r *macRecorder) Stop() []core.KeyEvent {
+	r.stopping.Store(true)
+	<-r.done
+	activeMacRecorderMu.Lock()
+	activeMacRecorder = nil
+	activeMacRecorderMu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events
+}
+
+//export akpRecordEvent
+func akpRecordEvent(typ C.int, code C.int, flags C.ulonglong) {
+	activeMacRecorderMu.Lock()
+	r := activeMacRecorder
+	activeMacRecorderMu.Unlock()
+	if r == nil {
+		return
+	}
+
+	event := core.KeyEvent{At: time.Since(r.start)}
+	switch typ {
+	case C.kCGEventKeyDown, C.kCGEventKeyUp:
+		key, ok := macRecordedKeys[code]
+		if !ok {
+			return
+		}
+		event.Key, event.Down = key, typ == C.kCGEventKeyDown
+	case C.kCGEventFlagsChanged:
+		key, ok := macModifierKeys[code]
+		if !ok {
+			return
+		}
+		event.Key, event.Down = key, C.CGEventFlags(flags)&macModifierFlags[key] != 0
+	default:
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
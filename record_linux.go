@@ -0,0 +1,164 @@
+//go:build linux
+
+package main
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"autokeypress/internal/core"
+)
+
+const (
+	procInputDevices = "/proc/bus/input/devices"
+
+	// evRep marks devices with key auto-repeat, which keyboards have and
+	// power buttons, lid switches and the like do not.
+	evRep = 0x14
+
+	keyValueUp   = 0
+	keyValueDown = 1
+)
+
+var inputEventSize = binary.Size(inputEvent{})
+
+// evdevKeys maps evdev codes back to keys. Both sides' modifiers count.
+var evdevKeys = func() map[uint16]core.Key {
+	keys := map[uint16]core.Key{
+		54:  core.KeyShift, // KEY_RIGHTSHIFT
+		97:  core.KeyCtrl,  // KEY_RIGHTCTRL
+		100: core.KeyAlt,   // KEY_RIGHTALT
+		126: core.KeyMeta,  // KEY_RIGHTMETA
+	}
+	for key, code := range linuxKeyCodes {
+		keys[code] = key
+	}
+	return keys
+}()
+
+// evdevRecorder reads key events straight from the keyboards' evdev devices,
+// so it works under X11, Wayland and on the console alike, but needs read
+// access to /dev/input (root, or membership of the input group).
+type evdevRecorder struct {
+	start time.Time
+	files []*os.File
+	wg    sync.WaitGroup
+
+	mu     sync.Mutex
+	events []core.KeyEvent
+}
+
+func startRecording() (keyRecorder, error) {
+	data, err := os.ReadFile(procInputDevices)
+	if err != nil {
+		return nil, err
+	}
+	paths := keyboardDevices(string(data))
+	if len(paths) == 0 {
+		return nil, errors.New("no keyboard found in " + procInputDevices)
+	}
+
+	r := &evdevRecorder{start: time.Now()}
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			r.close()
+			if errors.Is(err, os.ErrPermission) {
+				return nil, fmt.Errorf("recording needs read access to %s: run as root or add your user to the input group", path)
+			}
+			return nil, err
+		}
+		r.files = append(r.files, file)
+	}
+	for _, file := range r.files {
+		r.wg.Add(1)
+		go r.read(file)
+	}
+	return r, nil
+}
+
+func (r *evdevRecorder) read(file *os.File) {
+	defer r.wg.Done()
+	buf := make([]byte, 64*inputEventSize)
+	for {
+		n, err := file.Read(buf)
+		if err != nil {
+			return
+		}
+		for offset := 0; offset+inputEventSize <= n; offset += inputEventSize {
+			ev := decodeInputEvent(buf[offset : offset+inputEventSize])
+			key, ok := evdevKeys[ev.Code]
+			if ev.Type != evKey || !ok || (ev.Value != keyValueDown && ev.Value != keyValueUp) {
+				continue
+			}
+			at := time.Unix(ev.Time.Unix())
+			r.mu.Lock()
+			r.events = append(r.events, core.KeyEvent{
+				Key:  key,
+				Down: ev.Value == keyValueDown,
+				At:   max(at.Sub(r.start), 0),
+			})
+			r.mu.Unlock()
+		}
+	}
+}
+
+func (r *evdevRecorder) Stop() []core.KeyEvent {
+	r.close()
+	r.wg.Wait()
+	// Each keyboard is read on its own, so merge them by time.
+	slices.SortStableFunc(r.events, func(a, b core.KeyEvent) int {
+		return cmp.Compare(a.At, b.At)
+	})
+	return r.events
+}
+
+func (r *evdevRecorder) close() {
+	for _, file := range r.files {
+		file.Close()
+	}
+}
+
+// keyboardDevices returns the event devices listed in /proc/bus/input/devices
+// that have keys and auto-repeat. The uinput keyboard the app types with has
+// no auto-repeat, so its keys are not recorded.
+func keyboardDevices(devices string) []string {
+	var paths []string
+	for _, block := range strings.Split(devices, "\n\n") {
+		var (
+			event string
+			bits  uint64
+		)
+		for _, line := range strings.Split(block, "\n") {
+			switch {
+			case strings.HasPrefix(line, "H: Handlers="):
+				for _, handler := range strings.Fields(strings.TrimPrefix(line, "H: Handlers=")) {
+					if strings.HasPrefix(handler, "event") {
+						event = handler
+					}
+				}
+			case strings.HasPrefix(line, "B: EV="):
+				bits, _ = strconv.ParseUint(strings.TrimPrefix(line, "B: EV="), 16, 64)
+			}
+		}
+		if event != "" && bits&(1<<evKey) != 0 && bits&(1<<evRep) != 0 {
+			paths = append(paths, "/dev/input/"+event)
+		}
+	}
+	return paths
+}
+
+// decodeInputEvent reads one struct input_event, as uinput writes them.
+func decodeInputEvent(b []byte) inputEvent {
+	var ev inputEvent
+	binary.Decode(b, binary.NativeEndian, &ev)
+	return ev
+}
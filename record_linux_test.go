@@ -0,0 +1,72 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"syscall"
+	"testing"
+)
+
+const sampleInputDevices = `I: Bus=0019 Vendor=0000 Product=0001 Version=0000
+N: Name="Power Button"
+P: Phys=LNXPWRBN/button/input0
+S: Sysfs=/devices/LNXSYSTM:00/LNXPWRBN:00/input/input0
+U: Uniq=
+H: Handlers=kbd event0
+B: PROP=0
+B: EV=3
+B: KEY=10000000000000 0
+
+I: Bus=0011 Vendor=0001 Product=0001 Version=ab41
+N: Name="AT Translated Set 2 keyboard"
+P: Phys=isa0060/serio0/input0
+S: Sysfs=/devices/platform/i8042/serio0/input/input3
+U: Uniq=
+H: Handlers=sysrq kbd leds event3
+B: PROP=0
+B: EV=120013
+B: KEY=402000000 3803078f800d001 feffffdfffefffff fffffffffffffffe
+B: MSC=10
+B: LED=7
+
+I: Bus=0003 Vendor=046d Product=c52b Version=0111
+N: Name="Logitech USB Receiver Mouse"
+H: Handlers=mouse0 event5
+B: PROP=0
+B: EV=17
+B: KEY=ffff0000 0 0 0 0
+
+I: Bus=0003 Vendor=046d Product=c52b Version=0111
+N: Name="Logitech USB Receiver Keyboard"
+H: Handlers=sysrq kbd leds event6
+B: PROP=0
+B: EV=12001f
+`
+
+func TestKeyboardDevices(t *testing.T) {
+	got := keyboardDevices(sampleInputDevices)
+	want := []string{"/dev/input/event3", "/dev/input/event6"}
+	if !slices.Equal(got, want) {
+		t.Errorf("keyboardDevices = %q, want %q", got, want)
+	}
+}
+
+func TestDecodeInputEvent(t *testing.T) {
+	want := inputEvent{Time: syscall.Timeval{Sec: 1700000000, Usec: 250000}, Type: evKey, Code: 30, Value: keyValueDown}
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.NativeEndian, &want); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != inputEventSize {
+		t.Fatalf("encoded %d bytes, want %d", buf.Len(), inputEventSize)
+	}
+	if got := decodeInputEvent(buf.Bytes()); got != want {
+		t.Errorf("decodeInputEvent = %+v, want %+v", got, want)
+	}
+	if key := evdevKeys[want.Code]; key.String() != "A" {
+		t.Errorf("evdev code 30 = %v, want A", key)
+	}
+}
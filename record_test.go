@@ -0,0 +1,95 @@
+//go:build windows || darwin || linux
+
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"autokeypress/internal/core"
+	"autokeypress/internal/profile"
+)
+
+type fakeRecorder []core.KeyEvent
+
+func (r fakeRecorder) Stop() []core.KeyEvent { return r }
+
+// fakeKeyRecorder makes recordCommand record events, or fail to start when
+// err is not nil.
+func fakeKeyRecorder(t *testing.T, events []core.KeyEvent, err error) {
+	t.Helper()
+	saved := startKeyRecorder
+	startKeyRecorder = func() (keyRecorder, error) {
+		if err != nil {
+			return nil, err
+		}
+		return fakeRecorder(events), nil
+	}
+	t.Cleanup(func() { startKeyRecorder = saved })
+}
+
+func keyAt(key core.Key, down bool, ms int) core.KeyEvent {
+	return core.KeyEvent{Key: key, Down: down, At: time.Duration(ms) * time.Millisecond}
+}
+
+func TestRecordCommandExitCodes(t *testing.T) {
+	silence(t)
+	tests := []struct {
+		name   string
+		args   []string
+		events []core.KeyEvent
+		err    error
+		want   int
+	}{
+		{"help", []string{"--help"}, nil, nil, exitOK},
+		{"unknown flag", []string{"--bogus"}, nil, nil, exitUsage},
+		{"argument", []string{"extra"}, nil, nil, exitUsage},
+		{"negative quantize", []string{"--quantize", "-5"}, nil, nil, exitUsage},
+		{"recorder fails", []string{"--duration", "1ms"}, nil, errors.New("no keyboard"), exitError},
+		{"nothing recorded", []string{"--duration", "1ms"}, nil, nil, exitError},
+		{"recorded", []string{"--duration", "1ms"}, []core.KeyEvent{keyAt(core.KeyA, true, 0), keyAt(core.KeyA, false, 20)}, nil, exitOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeKeyRecorder(t, tt.events, tt.err)
+			if got := recordCommand(tt.args); got != tt.want {
+				t.Errorf("recordCommand(%q) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordCommandSave(t *testing.T) {
+	silence(t)
+	path := filepath.Join(t.TempDir(), "macros.yaml")
+	fakeKeyRecorder(t, []core.KeyEvent{
+		keyAt(core.KeyF9, true, 0), keyAt(core.KeyF9, false, 30),
+		keyAt(core.KeyH, true, 500), keyAt(core.KeyH, false, 530),
+		keyAt(core.KeyI, true, 540), keyAt(core.KeyI, false, 560),
+		keyAt(core.KeyEnter, true, 1000), keyAt(core.KeyEnter, false, 1020),
+	}, nil)
+
+	args := []string{"--duration", "1ms", "--trim-start", "1", "--quantize", "100", "--save", path}
+	for range 2 {
+		if got := recordCommand(args); got != exitOK {
+			t.Fatalf("recordCommand = %d, want %d", got, exitOK)
+		}
+	}
+
+	saved, err := profile.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Entries) != 2 {
+		t.Fatalf("saved %d entries, want one per recording", len(saved.Entries))
+	}
+	entry := saved.Entries[1]
+	if got, want := core.FormatSteps(entry.Steps), "type hi\nwait 500\nENTER"; got != want {
+		t.Errorf("steps = %q, want %q", got, want)
+	}
+	if entry.Key != macroName || entry.IntervalMS != 1500 || !entry.Enabled {
+		t.Errorf("entry = %+v, want an enabled %s repeating every 1500 ms", entry, macroName)
+	}
+}
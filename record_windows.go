@@ -0,0 +1,194 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"autokeypress/internal/core"
+)
+
+const (
+	whKeyboardLL = 13
+	wmQuit       = 0x0012
+	wmKeyDown    = 0x0100
+	wmKeyUp      = 0x0101
+	wmSysKeyDown = 0x0104
+	wmSysKeyUp   = 0x0105
+
+	llkhfExtended = 0x01
+	llkhfInjected = 0x10
+)
+
+var (
+	kernel32                = syscall.NewLazyDLL("kernel32.dll")
+	procGetCurrentThreadId  = kernel32.NewProc("GetCurrentThreadId")
+	procGetModuleHandle     = kernel32.NewProc("GetModuleHandleW")
+	procSetWindowsHookEx    = user32.NewProc("SetWindowsHookExW")
+	procUnhookWindowsHookEx = user32.NewProc("UnhookWindowsHookEx")
+	procCallNextHookEx      = user32.NewProc("CallNextHookEx")
+	procGetMessage          = user32.NewProc("GetMessageW")
+	procPostThreadMessage   = user32.NewProc("PostThreadMessageW")
+	keyboardHookCallback    = syscall.NewCallback(keyboardHook)
+)
+
+// activeRecorder receives the events from keyboardHook. The hook is global
+// to the process, so only one recording can run at a time.
+var (
+	activeRecorderMu sync.Mutex
+	activeRecorder   *windowsRecorder
+)
+
+type kbdllHookStruct struct {
+	VkCode    uint32
+	ScanCode  uint32
+	Flags     uint32
+	Time      uint32
+	ExtraInfo uintptr
+}
+
+type msg struct {
+	Hwnd    uintptr
+	Message uint32
+	WParam  uintptr
+	LParam  uintptr
+	Time    uint32
+	Pt      struct{ X, Y int32 }
+	_       uint32
+}
+
+// recordedScanCodes and recordedVKs map what the keyboard hook reports back
+// to keys, following windowsKeyCodes. Scan codes of extended keys have bit 8
+// set. The hook reports modifiers by side, so both sides are listed.
+var recordedScanCodes, recordedVKs = func() (map[uint32]core.Key, map[uint32]core.Key) {
+	scans := map[uint32]core.Key{}
+	vks := map[uint32]core.Key{
+		0xA0: core.KeyShift, 0xA1: core.KeyShift, // VK_LSHIFT, VK_RSHIFT
+		0xA2: core.KeyCtrl, 0xA3: core.KeyCtrl, // VK_LCONTROL, VK_RCONTROL
+		0xA4: core.KeyAlt, 0xA5: core.KeyAlt, // VK_LMENU, VK_RMENU
+		0x5B: core.KeyMeta, 0x5C: core.KeyMeta, // VK_LWIN, VK_RWIN
+	}
+	for key, code := range windowsKeyCodes {
+		switch {
+		case code >= vkOffset:
+			if _, ok := vks[uint32(code-vkOffset)]; !ok {
+				vks[uint32(code-vkOffset)] = key
+			}
+		case windowsExtendedKeys[key]:
+			scans[uint32(code)|0x100] = key
+		default:
+			scans[uint32(code)] = key
+		}
+	}
+	return scans, vks
+}()
+
+// windowsRecorder records through a low-level keyboard hook, which needs a
+// thread of its own running a message loop. Keys sent with SendInput are
+// flagged as injected and skipped.
+type windowsRecorder struct {
+	start  time.Time
+	thread uintptr
+	done   chan struct{}
+
+	mu     sync.Mutex
+	events []core.KeyEvent
+}
+
+func startRecording() (keyRecorder, error) {
+	activeRecorderMu.Lock()
+	defer activeRecorderMu.Unlock()
+	if activeRecorder != nil {
+		return nil, errors.New("a recording is already running")
+	}
+	r := &windowsRecorder{start: time.Now(), done: make(chan struct{})}
+	started := make(chan error, 1)
+	go r.run(started)
+	if err := <-started; err != nil {
+		return nil, err
+	}
+	activeRecorder = r
+	return r, nil
+}
+
+func (r *windowsRecorder) run(started chan<- error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	defer close(r.done)
+
+	r.thread, _, _ = procGetCurrentThreadId.Call()
+	module, _, _ := procGetModuleHandle.Call(0)
+	hook, _, err := procSetWindowsHookEx.Call(whKeyboardLL, keyboardHookCallback, module, 0)
+	if hook == 0 {
+		started <- fmt.Errorf("installing the keyboard hook: %w", err)
+		return
+	}
+	defer procUnhookWindowsHookEx.Call(hook)
+	started <- nil
+
+	var m msg
+	for {
+		ret, _, _ := procGetMessage.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+		if int32(ret) <= 0 {
+			return
+		}
+	}
+}
+
+func (r *windowsRecorder) Stop() []core.KeyEvent {
+	procPostThreadMessage.Call(r.thread, wmQuit, 0, 0)
+	<-r.done
+	activeRecorderMu.Lock()
+	activeRecorder = nil
+	activeRecorderMu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events
+}
+
+func (r *windowsRecorder) add(event core.KeyEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// keyboardHook runs on the recording thread for every key event in the
+// session.
+func keyboardHook(code uintptr, wParam uintptr, info *kbdllHookStruct) uintptr {
+	if int32(code) >= 0 && info.Flags&llkhfInjected == 0 {
+		activeRecorderMu.Lock()
+		r := activeRecorder
+		activeRecorderMu.Unlock()
+		if key, ok := recordedKey(info); ok && r != nil {
+			switch wParam {
+			case wmKeyDown, wmSysKeyDown:
+				r.add(core.KeyEvent{Key: key, Down: true, At: time.Since(r.start)})
+			case wmKeyUp, wmSysKeyUp:
+				r.add(core.KeyEvent{Key: key, At: time.Since(r.start)})
+			}
+		}
+	}
+	ret, _, _ := procCallNextHookEx.Call(0, code, wParam, uintptr(unsafe.Pointer(info)))
+	return ret
+}
+
+// recordedKey looks keys up by scan code first, as windowsKeyCodes mostly
+// sends them that way, and falls back to the virtual-key code for the rest.
+func recordedKey(info *kbdllHookStruct) (core.Key, bool) {
+	scan := info.ScanCode
+	if info.Flags&llkhfExtended != 0 {
+		scan |= 0x100
+	}
+	if key, ok := recordedScanCodes[scan]; ok {
+		return key, true
+	}
+	key, ok := recordedVKs[info.VkCode]
+	return key, ok
+}
@@ -0,0 +1,96 @@
+//go:build windows || darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"autokeypress/internal/core"
+)
+
+// statsRefresh is how often the lists redraw runner statistics.
+const statsRefresh = 500 * time.Millisecond
+
+// refreshStats calls refresh every statsRefresh until the app exits. Each UI
+// hands it a function that is safe to call from a background goroutine.
+func refreshStats(refresh func()) {
+	go func() {
+		ticker := time.NewTicker(statsRefresh)
+		defer ticker.Stop()
+		for range ticker.C {
+			refresh()
+		}
+	}()
+}
+
+func formatLastPress(stats core.TaskStats) string {
+	if stats.LastPress.IsZero() {
+		return ""
+	}
+	return stats.LastPress.Format("15:04:05")
+}
+
+// formatMeasured shows the measured mean interval and its drift from the
+// configured one, for example "1003 ms (+3)".
+func formatMeasured(stats core.TaskStats) string {
+	mean := stats.MeanInterval()
+	if mean == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d ms (%+d)", mean.Milliseconds(), stats.Drift().Milliseconds())
+}
+
+// statsSummary is the one-line form used by list rows.
+func statsSummary(stats core.TaskStats) string {
+	if stats.Presses == 0 {
+		return "no presses yet"
+	}
+	summary := fmt.Sprintf("%d presses, last %s", stats.Presses, formatLastPress(stats))
+	if stats.Failures > 0 {
+		summary += fmt.Sprintf(", %d failed", stats.Failures)
+	}
+	if lastError := formatLastError(stats); lastError != "" {
+		summary += " (" + lastError + ")"
+	}
+	if measured := formatMeasured(stats); measured != "" {
+		summary += ", actual " + measured
+	}
+	return summary
+}
+
+// formatLastError describes the task's most recent failure, noting when the
+// runner stopped the task because of it.
+func formatLastError(stats core.TaskStats) string {
+	if stats.LastError == nil {
+		return ""
+	}
+	if stats.GaveUp {
+		return fmt.Sprintf("stopped after %d failed in a row: %v", stats.FailStreak, stats.LastError)
+	}
+	return stats.LastError.Error()
+}
+
+// runningStatus is the status line of a run that has started pressing,
+// naming the keys whose latest press failed. ok is false before the first
+// press so the start countdown is left alone.
+func runningStatus(entries []*core.KeyEntry, stats map[*core.KeyEntry]core.TaskStats) (status string, ok bool) {
+	var failing []string
+	for _, entry := range entries {
+		entryStats, found := stats[entry]
+		if !found {
+			continue
+		}
+		if entryStats.Presses > 0 {
+			ok = true
+		}
+		if entryStats.FailStreak > 0 {
+			failing = append(failing, entry.DisplayKey())
+		}
+	}
+	if len(failing) == 0 {
+		return "Status: running", ok
+	}
+	return "Status: running - failing: " + strings.Join(failing, ", "), ok
+}
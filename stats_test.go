@@ -0,0 +1,129 @@
+//go:build windows || darwin || linux
+
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"autokeypress/internal/core"
+)
+
+func TestStatsFormatting(t *testing.T) {
+	last := time.Date(2026, 5, 1, 9, 5, 7, 0, time.Local)
+	tests := []struct {
+		name    string
+		stats   core.TaskStats
+		last    string
+		summary string
+	}{
+		{
+			name:    "no presses",
+			stats:   core.TaskStats{Interval: time.Second},
+			summary: "no presses yet",
+		},
+		{
+			name:    "one press",
+			stats:   core.TaskStats{Presses: 1, LastPress: last, Interval: time.Second},
+			last:    "09:05:07",
+			summary: "1 presses, last 09:05:07",
+		},
+		{
+			name:    "one failed press",
+			stats:   core.TaskStats{Presses: 1, Failures: 1, LastPress: last, LastError: errors.New("refused")},
+			last:    "09:05:07",
+			summary: "1 presses, last 09:05:07, 1 failed (refused)",
+		},
+		{
+			name: "gave up",
+			stats: core.TaskStats{Presses: 1, Failures: 1, FailStreak: 1, GaveUp: true,
+				LastPress: last, LastError: errors.New("refused")},
+			last:    "09:05:07",
+			summary: "1 presses, last 09:05:07, 1 failed (stopped after 1 failed in a row: refused)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatLastPress(tt.stats); got != tt.last {
+				t.Errorf("formatLastPress = %q, want %q", got, tt.last)
+			}
+			if got := formatMeasured(tt.stats); got != "" {
+				t.Errorf("formatMeasured = %q before a second press", got)
+			}
+			if got := statsSummary(tt.stats); got != tt.summary {
+				t.Errorf("statsSummary = %q, want %q", got, tt.summary)
+			}
+		})
+	}
+}
+
+// TestStatsFormattingMeasured takes its stats from a real run, since the
+// measured interval depends on when the presses happened.
+func TestStatsFormattingMeasured(t *testing.T) {
+	entry := &core.KeyEntry{Key: "F5", IntervalMS: 5, Enabled: true, MaxPresses: 3}
+	injector := &countingInjector{presses: map[core.Key]int{}}
+	tasks, errs := core.BuildTasks([]*core.KeyEntry{entry}, injector)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	runner := core.NewRunner(injector)
+	finished := make(chan struct{})
+	runner.OnFinished(func() { close(finished) })
+	runner.Start(tasks)
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		runner.Stop()
+		t.Fatal("the run did not finish on its own")
+	}
+
+	stats := runner.Stats()[entry]
+	measured := formatMeasured(stats)
+	if !regexp.MustCompile(`^\d+ ms \([+-]\d+\)$`).MatchString(measured) {
+		t.Errorf("formatMeasured = %q, want \"<ms> ms (<drift>)\"", measured)
+	}
+	summary := statsSummary(stats)
+	if !strings.HasPrefix(summary, "3 presses, last ") || !strings.HasSuffix(summary, ", actual "+measured) {
+		t.Errorf("statsSummary = %q", summary)
+	}
+}
+
+func TestRunningStatus(t *testing.T) {
+	f5 := &core.KeyEntry{Key: "F5"}
+	f6 := &core.KeyEntry{Key: "F6"}
+	entries := []*core.KeyEntry{f5, f6}
+	tests := []struct {
+		name   string
+		stats  map[*core.KeyEntry]core.TaskStats
+		status string
+		ok     bool
+	}{
+		{
+			name:  "not pressed yet",
+			stats: map[*core.KeyEntry]core.TaskStats{f5: {}, f6: {}},
+		},
+		{
+			name:   "all fine",
+			stats:  map[*core.KeyEntry]core.TaskStats{f5: {Presses: 2}, f6: {Presses: 1, Failures: 1}},
+			status: "Status: running",
+			ok:     true,
+		},
+		{
+			name:   "failing keys",
+			stats:  map[*core.KeyEntry]core.TaskStats{f5: {Presses: 2, FailStreak: 1}, f6: {Presses: 3, FailStreak: 3, GaveUp: true}},
+			status: "Status: running - failing: F5, F6",
+			ok:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, ok := runningStatus(entries, tt.stats)
+			if ok != tt.ok || (ok && status != tt.status) {
+				t.Errorf("runningStatus = %q, %t; want %q, %t", status, ok, tt.status, tt.ok)
+			}
+		})
+	}
+}
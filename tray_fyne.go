@@ -0,0 +1,104 @@
+//go:build darwin || (linux && gui)
+
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"path/filepath"
+	"strings"
+
+	"autokeypress/internal/profile"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+var (
+	idleTrayIcon    = circleIcon("tray-idle.png", color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff})
+	runningTrayIcon = circleIcon("tray-running.png", color.NRGBA{R: 0x2e, G: 0xa0, B: 0x43, A: 0xff})
+)
+
+// trayMenu is the system tray icon and its menu. It lets the window be hidden
+// while the keys run over another app.
+type trayMenu struct {
+	desk        desktop.App
+	show        func()
+	start       func()
+	stop        func()
+	loadProfile func(name string)
+}
+
+// newTrayMenu returns nil when the driver has no system tray.
+func newTrayMenu(application fyne.App, show, start, stop func(), loadProfile func(name string)) *trayMenu {
+	desk, ok := application.(desktop.App)
+	if !ok {
+		return nil
+	}
+	return &trayMenu{desk: desk, show: show, start: start, stop: stop, loadProfile: loadProfile}
+}
+
+// update rebuilds the menu and swaps the icon for the running state. Saved
+// profiles are listed again each time so newly saved ones show up; the one
+// at currentPath is ticked, and switching is disabled while keys run, as in
+// File > Load.
+func (t *trayMenu) update(running bool, currentPath string) {
+	if t == nil {
+		return
+	}
+	current := strings.TrimSuffix(filepath.Base(currentPath), filepath.Ext(currentPath))
+	startItem := fyne.NewMenuItem("Start", t.start)
+	startItem.Disabled = running
+	stopItem := fyne.NewMenuItem("Stop", t.stop)
+	stopItem.Disabled = !running
+
+	profilesItem := fyne.NewMenuItem("Profile", nil)
+	var profileItems []*fyne.MenuItem
+	names, _ := profile.List()
+	for _, name := range names {
+		item := fyne.NewMenuItem(name, func() { t.loadProfile(name) })
+		item.Checked = currentPath != "" && name == current
+		item.Disabled = running
+		profileItems = append(profileItems, item)
+	}
+	if len(profileItems) == 0 {
+		empty := fyne.NewMenuItem("No saved profiles", nil)
+		empty.Disabled = true
+		profileItems = append(profileItems, empty)
+	}
+	profilesItem.ChildMenu = fyne.NewMenu("", profileItems...)
+
+	t.desk.SetSystemTrayMenu(fyne.NewMenu("Auto Key Presser",
+		fyne.NewMenuItem("Show Window", t.show),
+		fyne.NewMenuItemSeparator(),
+		startItem,
+		stopItem,
+		fyne.NewMenuItemSeparator(),
+		profilesItem,
+	))
+	if running {
+		t.desk.SetSystemTrayIcon(runningTrayIcon)
+	} else {
+		t.desk.SetSystemTrayIcon(idleTrayIcon)
+	}
+}
+
+// circleIcon draws a filled circle so the tray needs no image assets.
+func circleIcon(name string, fill color.Color) fyne.Resource {
+	const size = 64
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	center := float64(size-1) / 2
+	radius := float64(size)/2 - 2
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := float64(x)-center, float64(y)-center
+			if dx*dx+dy*dy <= radius*radius {
+				img.Set(x, y, fill)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return fyne.NewStaticResource(name, buf.Bytes())
+}
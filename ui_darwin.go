@@ -0,0 +1,17 @@
+//go:build darwin
+
+package main
+
+import "autokeypress/internal/core"
+
+// openUIInjector returns the injector behind the window. A missing
+// Accessibility permission is not fatal here: the window still opens and
+// checkInjector reports it when Start is pressed.
+func openUIInjector() (core.Injector, func(), error) {
+	return &macInjector{}, func() {}, nil
+}
+
+// checkInjector runs before every start.
+func checkInjector() error {
+	return checkAccessibility()
+}
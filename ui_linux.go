@@ -0,0 +1,17 @@
+//go:build linux && gui
+
+package main
+
+import "autokeypress/internal/core"
+
+// openUIInjector opens the XTEST or uinput backend, see openLinuxKeyboard.
+// The window opens even when neither is available so the error can be shown.
+func openUIInjector() (core.Injector, func(), error) {
+	return openInjector()
+}
+
+// checkInjector runs before every start. The backend was chosen when the
+// window opened, so there is nothing left to check.
+func checkInjector() error {
+	return nil
+}
@@ -0,0 +1,302 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"autokeypress/internal/core"
+)
+
+const (
+	evSyn        = 0x00
+	evKey        = 0x01
+	evRel        = 0x02
+	synReport    = 0
+	keyLeftShift = 42
+
+	btnLeft   = 0x110
+	btnRight  = 0x111
+	btnMiddle = 0x112
+	relX      = 0x00
+	relY      = 0x01
+	relWheel  = 0x08
+
+	uiSetEvBit   = 0x40045564
+	uiSetKeyBit  = 0x40045565
+	uiSetRelBit  = 0x40045566
+	uiDevCreate  = 0x5501
+	uiDevDestroy = 0x5502
+
+	busVirtual = 0x06
+)
+
+type inputEvent struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+type uinputUserDev struct {
+	Name         [80]byte
+	Bustype      uint16
+	Vendor       uint16
+	Product      uint16
+	Version      uint16
+	FFEffectsMax uint32
+	Absmax       [64]int32
+	Absmin       [64]int32
+	Absfuzz      [64]int32
+	Absflat      [64]int32
+}
+
+// uinputKeyboard is a virtual keyboard and mouse registered through
+// /dev/uinput. It works on X11, Wayland and the console alike but needs
+// write access to the device node (root, or a udev rule granting the input
+// group). Like a real mouse it can only move the pointer by an offset.
+type uinputKeyboard struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func openUinputKeyboard() (*uinputKeyboard, error) {
+	file, err := os.OpenFile("/dev/uinput", os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("uinput: %w", err)
+	}
+
+	if err := ioctl(file, uiSetEvBit, evKey); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("uinput set EV_KEY: %w", err)
+	}
+	for code := 1; code < 256; code++ {
+		if err := ioctl(file, uiSetKeyBit, uintptr(code)); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("uinput set key %d: %w", code, err)
+		}
+	}
+	for _, code := range []uintptr{btnLeft, btnRight, btnMiddle} {
+		if err := ioctl(file, uiSetKeyBit, code); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("uinput set button %d: %w", code, err)
+		}
+	}
+	if err := ioctl(file, uiSetEvBit, evRel); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("uinput set EV_REL: %w", err)
+	}
+	for _, code := range []uintptr{relX, relY, relWheel} {
+		if err := ioctl(file, uiSetRelBit, code); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("uinput set axis %d: %w", code, err)
+		}
+	}
+
+	dev := uinputUserDev{
+		Bustype: busVirtual,
+		Vendor:  0x1,
+		Product: 0x1,
+		Version: 1,
+	}
+	copy(dev.Name[:], "autokeypress virtual keyboard")
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.NativeEndian, &dev)
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("uinput setup: %w", err)
+	}
+	if err := ioctl(file, uiDevCreate, 0); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("uinput create device: %w", err)
+	}
+
+	// Give udev and the compositor a moment to pick up the new device,
+	// otherwise the first presses are dropped.
+	time.Sleep(200 * time.Millisecond)
+
+	return &uinputKeyboard{file: file}, nil
+}
+
+func (k *uinputKeyboard) Key(code uint16, down bool) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.key(code, down)
+}
+
+func (k *uinputKeyboard) TapRune(r rune) error {
+	code, shift, ok := linuxRuneKeyCode(r)
+	if !ok {
+		return fmt.Errorf("unsupported key: %c", r)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if shift {
+		if err := k.key(keyLeftShift, true); err != nil {
+			return err
+		}
+		defer k.key(keyLeftShift, false)
+	}
+	if err := k.key(code, true); err != nil {
+		return err
+	}
+	return k.key(code, false)
+}
+
+// Button presses or releases a mouse button. Buttons are keys to evdev.
+func (k *uinputKeyboard) Button(code uint16, down bool) error {
+	return k.Key(code, down)
+}
+
+func (k *uinputKeyboard) Move(x, y int, relative bool) error {
+	if !relative {
+		return fmt.Errorf("uinput: cannot move the pointer to a position, only by an offset (MOVEBY); the XTEST backend can")
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.emit(evRel, relX, int32(x)); err != nil {
+		return err
+	}
+	if err := k.emit(evRel, relY, int32(y)); err != nil {
+		return err
+	}
+	return k.emit(evSyn, synReport, 0)
+}
+
+func (k *uinputKeyboard) Scroll(notches int) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.emit(evRel, relWheel, int32(notches)); err != nil {
+		return err
+	}
+	return k.emit(evSyn, synReport, 0)
+}
+
+func (k *uinputKeyboard) Close() error {
+	_ = ioctl(k.file, uiDevDestroy, 0)
+	return k.file.Close()
+}
+
+func (k *uinputKeyboard) key(code uint16, down bool) error {
+	var value int32
+	if down {
+		value = 1
+	}
+	if err := k.emit(evKey, code, value); err != nil {
+		return err
+	}
+	return k.emit(evSyn, synReport, 0)
+}
+
+func (k *uinputKeyboard) emit(typ, code uint16, value int32) error {
+	ev := inputEvent{Type: typ, Code: code, Value: value}
+	return binary.Write(k.file, binary.NativeEndian, &ev)
+}
+
+func ioctl(file *os.File, request, arg uintptr) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), request, arg)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// linuxRuneKeyCode maps a printable ASCII rune to its evdev code on a US
+// layout. uinput has no notion of text, so runes outside this table cannot
+// be typed.
+func linuxRuneKeyCode(r rune) (code uint16, shift bool, ok bool) {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return linuxKeyCodes[core.KeyA+core.Key(r-'a')], false, true
+	case r >= 'A' && r <= 'Z':
+		return linuxKeyCodes[core.KeyA+core.Key(r-'A')], true, true
+	case r >= '0' && r <= '9':
+		return linuxKeyCodes[core.Key0+core.Key(r-'0')], false, true
+	}
+
+	switch r {
+	case ' ':
+		return 57, false, true
+	case '\n':
+		return 28, false, true
+	case '\t':
+		return 15, false, true
+	case '-':
+		return 12, false, true
+	case '_':
+		return 12, true, true
+	case '=':
+		return 13, false, true
+	case '+':
+		return 13, true, true
+	case '[':
+		return 26, false, true
+	case '{':
+		return 26, true, true
+	case ']':
+		return 27, false, true
+	case '}':
+		return 27, true, true
+	case ';':
+		return 39, false, true
+	case ':':
+		return 39, true, true
+	case '\'':
+		return 40, false, true
+	case '"':
+		return 40, true, true
+	case '`':
+		return 41, false, true
+	case '~':
+		return 41, true, true
+	case '\\':
+		return 43, false, true
+	case '|':
+		return 43, true, true
+	case ',':
+		return 51, false, true
+	case '<':
+		return 51, true, true
+	case '.':
+		return 52, false, true
+	case '>':
+		return 52, true, true
+	case '/':
+		return 53, false, true
+	case '?':
+		return 53, true, true
+	case '!':
+		return 2, true, true
+	case '@':
+		return 3, true, true
+	case '#':
+		return 4, true, true
+	case '$':
+		return 5, true, true
+	case '%':
+		return 6, true, true
+	case '^':
+		return 7, true, true
+	case '&':
+		return 8, true, true
+	case '*':
+		return 9, true, true
+	case '(':
+		return 10, true, true
+	case ')':
+		return 11, true, true
+	default:
+		return 0, false, false
+	}
+}
@@ -0,0 +1,381 @@
+//go:build linux && cgo
+
+package main
+
+/*
+#cgo LDFLAGS: -ldl
+#include <dlfcn.h>
+#include <stddef.h>
+
+// libX11 and libXtst are loaded at runtime so the binary still starts on
+// machines without X libraries (servers, Wayland-only, the console) and can
+// fall back to uinput there.
+
+// akpXErrorEvent mirrors the start of Xlib's XErrorEvent.
+typedef struct {
+	int type;
+	void *display;
+	unsigned long resourceid;
+	unsigned long serial;
+	unsigned char error_code;
+	unsigned char request_code;
+	unsigned char minor_code;
+} akpXErrorEvent;
+
+typedef int (*akpXErrorHandler)(void *, akpXErrorEvent *);
+
+static void *(*pXOpenDisplay)(const char *);
+static int (*pXCloseDisplay)(void *);
+static int (*pXSync)(void *, int);
+static akpXErrorHandler (*pXSetErrorHandler)(akpXErrorHandler);
+static int (*pXDisplayKeycodes)(void *, int *, int *);
+static unsigned char (*pXKeysymToKeycode)(void *, unsigned long);
+static unsigned long *(*pXGetKeyboardMapping)(void *, unsigned char, int, int *);
+static int (*pXChangeKeyboardMapping)(void *, int, int, unsigned long *, int);
+static int (*pXFree)(void *);
+static int (*pXTestQueryExtension)(void *, int *, int *, int *, int *);
+static int (*pXTestFakeKeyEvent)(void *, unsigned int, int, unsigned long);
+static int (*pXTestFakeButtonEvent)(void *, unsigned int, int, unsigned long);
+static int (*pXTestFakeMotionEvent)(void *, int, int, int, unsigned long);
+static unsigned long (*pXDefaultRootWindow)(void *);
+static int (*pXQueryPointer)(void *, unsigned long, unsigned long *, unsigned long *, int *, int *, int *, int *, unsigned int *);
+
+static int akpLoadXTest(void) {
+	void *x11 = dlopen("libX11.so.6", RTLD_NOW | RTLD_GLOBAL);
+	void *xtst = dlopen("libXtst.so.6", RTLD_NOW | RTLD_GLOBAL);
+	if (x11 == NULL || xtst == NULL) {
+		return 0;
+	}
+	pXOpenDisplay = dlsym(x11, "XOpenDisplay");
+	pXCloseDisplay = dlsym(x11, "XCloseDisplay");
+	pXSync = dlsym(x11, "XSync");
+	pXSetErrorHandler = dlsym(x11, "XSetErrorHandler");
+	pXDisplayKeycodes = dlsym(x11, "XDisplayKeycodes");
+	pXKeysymToKeycode = dlsym(x11, "XKeysymToKeycode");
+	pXGetKeyboardMapping = dlsym(x11, "XGetKeyboardMapping");
+	pXChangeKeyboardMapping = dlsym(x11, "XChangeKeyboardMapping");
+	pXFree = dlsym(x11, "XFree");
+	pXTestQueryExtension = dlsym(xtst, "XTestQueryExtension");
+	pXTestFakeKeyEvent = dlsym(xtst, "XTestFakeKeyEvent");
+	pXTestFakeButtonEvent = dlsym(xtst, "XTestFakeButtonEvent");
+	pXTestFakeMotionEvent = dlsym(xtst, "XTestFakeMotionEvent");
+	pXDefaultRootWindow = dlsym(x11, "XDefaultRootWindow");
+	pXQueryPointer = dlsym(x11, "XQueryPointer");
+	return pXOpenDisplay && pXCloseDisplay && pXSync && pXSetErrorHandler &&
+		pXDisplayKeycodes && pXKeysymToKeycode && pXGetKeyboardMapping &&
+		pXChangeKeyboardMapping && pXFree && pXTestQueryExtension &&
+		pXTestFakeKeyEvent && pXTestFakeButtonEvent && pXTestFakeMotionEvent &&
+		pXDefaultRootWindow && pXQueryPointer;
+}
+
+static void *akpOpenDisplay(void) {
+	return pXOpenDisplay(NULL);
+}
+
+static void akpCloseDisplay(void *display) {
+	pXCloseDisplay(display);
+}
+
+static int akpHasXTest(void *display) {
+	int eventBase, errorBase, major, minor;
+	return pXTestQueryExtension(display, &eventBase, &errorBase, &major, &minor);
+}
+
+// The akpFake functions return 0 when Xlib could not queue the request.
+
+static int akpFakeKey(void *display, unsigned int keycode, int down) {
+	return pXTestFakeKeyEvent(display, keycode, down, 0);
+}
+
+static int akpFakeButton(void *display, unsigned int button, int down) {
+	return pXTestFakeButtonEvent(display, button, down, 0);
+}
+
+// akpMovePointer moves the pointer to x, y, or by them when relative. XTEST's
+// own relative motion changed signature between versions, so relative moves
+// start from the position XQueryPointer reports.
+static int akpMovePointer(void *display, int x, int y, int relative) {
+	if (relative) {
+		unsigned long root, child;
+		int rootX = 0, rootY = 0, winX, winY;
+		unsigned int mask;
+		pXQueryPointer(display, pXDefaultRootWindow(display), &root, &child, &rootX, &rootY, &winX, &winY, &mask);
+		x += rootX;
+		y += rootY;
+	}
+	return pXTestFakeMotionEvent(display, -1, x, y, 0);
+}
+
+static int akpXError;
+
+static int akpOnXError(void *display, akpXErrorEvent *event) {
+	akpXError = event->error_code;
+	return 0;
+}
+
+// akpSync waits for the server to handle the requests sent so far and
+// returns the code of the last X error it answered with, or 0. Xlib's own
+// handler would print the error and exit, so another one is installed for
+// the wait, the way GLFW does around its own requests.
+static int akpSync(void *display) {
+	akpXError = 0;
+	akpXErrorHandler previous = pXSetErrorHandler(akpOnXError);
+	pXSync(display, 0);
+	pXSetErrorHandler(previous);
+	return akpXError;
+}
+
+static unsigned char akpKeysymToKeycode(void *display, unsigned long keysym) {
+	return pXKeysymToKeycode(display, keysym);
+}
+
+// akpKeycodeLevel reports which shift level of keycode produces keysym, or -1.
+static int akpKeycodeLevel(void *display, unsigned char keycode, unsigned long keysym) {
+	int perCode = 0;
+	unsigned long *syms = pXGetKeyboardMapping(display, keycode, 1, &perCode);
+	int level = -1;
+	if (syms == NULL) {
+		return level;
+	}
+	for (int i = 0; i < perCode && i < 2; i++) {
+		if (syms[i] == keysym) {
+			level = i;
+			break;
+		}
+	}
+	pXFree(syms);
+	return level;
+}
+
+// akpSpareKeycode returns a keycode with no keysyms bound, or 0.
+static int akpSpareKeycode(void *display) {
+	int minCode = 0, maxCode = 0, perCode = 0;
+	pXDisplayKeycodes(display, &minCode, &maxCode);
+	unsigned long *syms = pXGetKeyboardMapping(display, minCode, maxCode - minCode + 1, &perCode);
+	if (syms == NULL) {
+		return 0;
+	}
+	int spare = 0;
+	for (int code = maxCode; code >= minCode && spare == 0; code--) {
+		int empty = 1;
+		for (int i = 0; i < perCode; i++) {
+			if (syms[(code - minCode) * perCode + i] != 0) {
+				empty = 0;
+				break;
+			}
+		}
+		if (empty) {
+			spare = code;
+		}
+	}
+	pXFree(syms);
+	return spare;
+}
+
+static int akpBindKeycode(void *display, int keycode, unsigned long keysym) {
+	unsigned long syms[2] = {keysym, keysym};
+	pXChangeKeyboardMapping(display, keycode, 2, syms, 1);
+	return akpSync(display);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+const (
+	xKeycodeOffset = 8
+	xShiftKeycode  = keyLeftShift + xKeycodeOffset
+
+	xWheelUp   = 4
+	xWheelDown = 5
+)
+
+// xButtons maps evdev button codes to X pointer buttons.
+var xButtons = map[uint16]C.uint{btnLeft: 1, btnMiddle: 2, btnRight: 3}
+
+var (
+	xtestLoadOnce sync.Once
+	xtestLoaded   bool
+)
+
+// xtestKeyboard injects keys through the X11 XTEST extension. Unlike uinput
+// it needs no special permissions, only a reachable $DISPLAY, which also
+// makes it usable against a headless Xvfb server.
+type xtestKeyboard struct {
+	mu      sync.Mutex
+	display unsafe.Pointer
+	spare   C.int
+}
+
+func openXTestKeyboard() (*xtestKeyboard, error) {
+	xtestLoadOnce.Do(func() {
+		xtestLoaded = C.akpLoadXTest() != 0
+	})
+	if !xtestLoaded {
+		return nil, fmt.Errorf("xtest: libX11/libXtst not available")
+	}
+
+	display := C.akpOpenDisplay()
+	if display == nil {
+		return nil, fmt.Errorf("xtest: cannot open display")
+	}
+	if C.akpHasXTest(display) == 0 {
+		C.akpCloseDisplay(display)
+		return nil, fmt.Errorf("xtest: XTEST extension not supported by the X server")
+	}
+
+	return &xtestKeyboard{
+		display: display,
+		spare:   C.int(C.akpSpareKeycode(display)),
+	}, nil
+}
+
+// Key presses or releases an evdev key code. X servers using the
+// evdev/libinput drivers (and Xvfb) number their keycodes as the evdev code
+// plus 8.
+func (k *xtestKeyboard) Key(code uint16, down bool) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var pressed C.int
+	if down {
+		pressed = 1
+	}
+	sent := C.akpFakeKey(k.display, C.uint(code)+xKeycodeOffset, pressed) != 0
+	return k.sync(sent, fmt.Sprintf("key %d", code))
+}
+
+// TapRune types r. If the current keymap already has the rune's keysym it is
+// pressed directly (with Shift for the second level); otherwise the keysym is
+// temporarily bound to a spare keycode, pressed, and the binding removed.
+func (k *xtestKeyboard) TapRune(r rune) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	keysym := runeKeysym(r)
+	if keycode := C.akpKeysymToKeycode(k.display, keysym); keycode != 0 {
+		switch C.akpKeycodeLevel(k.display, keycode, keysym) {
+		case 0:
+			return k.tap(C.uint(keycode), false, r)
+		case 1:
+			return k.tap(C.uint(keycode), true, r)
+		}
+	}
+
+	if k.spare == 0 {
+		return fmt.Errorf("xtest: no spare keycode to type %q", r)
+	}
+	if code := C.akpBindKeycode(k.display, k.spare, keysym); code != 0 {
+		return fmt.Errorf("xtest: binding %q: X error %d", r, int(code))
+	}
+	err := k.tap(C.uint(k.spare), false, r)
+	// Clients resolve the keysym when they process the event, so keep the
+	// binding around briefly before clearing it.
+	time.Sleep(20 * time.Millisecond)
+	if code := C.akpBindKeycode(k.display, k.spare, 0); code != 0 && err == nil {
+		err = fmt.Errorf("xtest: unbinding %q: X error %d", r, int(code))
+	}
+	return err
+}
+
+func (k *xtestKeyboard) Button(code uint16, down bool) error {
+	button, ok := xButtons[code]
+	if !ok {
+		return fmt.Errorf("xtest: unsupported button %d", code)
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var pressed C.int
+	if down {
+		pressed = 1
+	}
+	sent := C.akpFakeButton(k.display, button, pressed) != 0
+	return k.sync(sent, fmt.Sprintf("button %d", code))
+}
+
+func (k *xtestKeyboard) Move(x, y int, relative bool) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var rel C.int
+	if relative {
+		rel = 1
+	}
+	sent := C.akpMovePointer(k.display, C.int(x), C.int(y), rel) != 0
+	return k.sync(sent, "move")
+}
+
+// Scroll clicks the X wheel buttons, one click per notch.
+func (k *xtestKeyboard) Scroll(notches int) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	button := C.uint(xWheelUp)
+	if notches < 0 {
+		button, notches = xWheelDown, -notches
+	}
+	sent := true
+	for range notches {
+		sent = C.akpFakeButton(k.display, button, 1) != 0 && sent
+		sent = C.akpFakeButton(k.display, button, 0) != 0 && sent
+	}
+	return k.sync(sent, "scroll")
+}
+
+func (k *xtestKeyboard) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	C.akpCloseDisplay(k.display)
+	return nil
+}
+
+// tap presses and releases keycode to type r. Every event is sent even if
+// one fails, so Shift is not left down.
+func (k *xtestKeyboard) tap(keycode C.uint, shift bool, r rune) error {
+	sent := true
+	if shift {
+		sent = C.akpFakeKey(k.display, xShiftKeycode, 1) != 0
+	}
+	sent = C.akpFakeKey(k.display, keycode, 1) != 0 && sent
+	sent = C.akpFakeKey(k.display, keycode, 0) != 0 && sent
+	if shift {
+		sent = C.akpFakeKey(k.display, xShiftKeycode, 0) != 0 && sent
+	}
+	return k.sync(sent, fmt.Sprintf("typing %q", r))
+}
+
+// sync waits for the server to handle what was sent. sent is false when
+// Xlib could not queue one of the requests; otherwise the X error the
+// server answered with, if any, is returned.
+func (k *xtestKeyboard) sync(sent bool, action string) error {
+	code := C.akpSync(k.display)
+	switch {
+	case !sent:
+		return fmt.Errorf("xtest: %s: request not sent", action)
+	case code != 0:
+		return fmt.Errorf("xtest: %s: X error %d", action, int(code))
+	}
+	return nil
+}
+
+// runeKeysym converts a rune to an X keysym: Latin-1 characters share their
+// code point, everything else uses the 0x01000000 Unicode keysym range.
+func runeKeysym(r rune) C.ulong {
+	switch {
+	case r == '\n':
+		return 0xff0d // XK_Return
+	case r == '\t':
+		return 0xff09 // XK_Tab
+	case (r >= 0x20 && r <= 0x7e) || (r >= 0xa0 && r <= 0xff):
+		return C.ulong(r)
+	default:
+		return C.ulong(0x01000000 | r)
+	}
+}
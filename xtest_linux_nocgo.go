@@ -0,0 +1,35 @@
+//go:build linux && !cgo
+
+package main
+
+import "fmt"
+
+type xtestKeyboard struct{}
+
+func openXTestKeyboard() (*xtestKeyboard, error) {
+	return nil, fmt.Errorf("xtest: built without cgo")
+}
+
+func (k *xtestKeyboard) Key(code uint16, down bool) error {
+	return fmt.Errorf("xtest: built without cgo")
+}
+
+func (k *xtestKeyboard) TapRune(r rune) error {
+	return fmt.Errorf("xtest: built without cgo")
+}
+
+func (k *xtestKeyboard) Button(code uint16, down bool) error {
+	return fmt.Errorf("xtest: built without cgo")
+}
+
+func (k *xtestKeyboard) Move(x, y int, relative bool) error {
+	return fmt.Errorf("xtest: built without cgo")
+}
+
+func (k *xtestKeyboard) Scroll(notches int) error {
+	return fmt.Errorf("xtest: built without cgo")
+}
+
+func (k *xtestKeyboard) Close() error {
+	return nil
+}
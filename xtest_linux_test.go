@@ -0,0 +1,86 @@
+//go:build linux && cgo
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startXvfb starts a private Xvfb server for the test and returns its
+// display name, skipping the test when Xvfb is not installed.
+func startXvfb(t *testing.T) string {
+	t.Helper()
+	xvfb, err := exec.LookPath("Xvfb")
+	if err != nil {
+		t.Skip("Xvfb is not installed")
+	}
+	// Xvfb picks a free display and writes its number to -displayfd once
+	// it accepts connections.
+	read, write, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer read.Close()
+	cmd := exec.Command(xvfb, "-displayfd", "3", "-nolisten", "tcp", "-screen", "0", "640x480x24")
+	cmd.ExtraFiles = []*os.File{write}
+	if err := cmd.Start(); err != nil {
+		write.Close()
+		t.Fatalf("starting Xvfb: %v", err)
+	}
+	write.Close()
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	number := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(read).ReadString('\n')
+		number <- strings.TrimSpace(line)
+	}()
+	select {
+	case n := <-number:
+		if n == "" {
+			t.Fatal("Xvfb exited without a display")
+		}
+		return ":" + n
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for Xvfb")
+		return ""
+	}
+}
+
+func TestXTestKeyboardOnXvfb(t *testing.T) {
+	t.Setenv("DISPLAY", startXvfb(t))
+	keyboard, err := openXTestKeyboard()
+	if err != nil {
+		t.Fatalf("openXTestKeyboard: %v", err)
+	}
+	defer keyboard.Close()
+
+	const keyA = 30
+	steps := []struct {
+		name string
+		do   func() error
+	}{
+		{"press A", func() error { return keyboard.Key(keyA, true) }},
+		{"release A", func() error { return keyboard.Key(keyA, false) }},
+		{"press the left button", func() error { return keyboard.Button(btnLeft, true) }},
+		{"release the left button", func() error { return keyboard.Button(btnLeft, false) }},
+		{"move to 100 100", func() error { return keyboard.Move(100, 100, false) }},
+		{"move by -10 5", func() error { return keyboard.Move(-10, 5, true) }},
+		{"scroll down", func() error { return keyboard.Scroll(-2) }},
+		{"type x", func() error { return keyboard.TapRune('x') }},
+		{"type é", func() error { return keyboard.TapRune('é') }},
+	}
+	for _, step := range steps {
+		if err := step.do(); err != nil {
+			t.Errorf("%s: %v", step.name, err)
+		}
+	}
+}